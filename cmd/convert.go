@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Votline/Gurlf/pkg/convert"
+)
+
+// runConvert translates a file, or stdin, between gurlf, JSON, YAML and
+// TOML. -from defaults to the file extension.
+func runConvert(log *zap.Logger, args []string) int {
+	fl := flag.NewFlagSet("convert", flag.ContinueOnError)
+	from := fl.String("from", "", "input format: "+strings.Join(convert.Formats, ", "))
+	to := fl.String("to", "gurlf", "output format")
+	out := fl.String("o", "", "write to file instead of stdout")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+	if fl.NArg() > 1 {
+		log.Error("Usage: convert [-from FORMAT] [-to FORMAT] [-o FILE] [FILE]")
+		return 2
+	}
+
+	var src []byte
+	var err error
+	if fl.NArg() == 0 {
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		src, err = os.ReadFile(fl.Arg(0))
+		if *from == "" {
+			*from = strings.TrimPrefix(filepath.Ext(fl.Arg(0)), ".")
+			if *from == "yml" {
+				*from = "yaml"
+			}
+		}
+	}
+	if err != nil {
+		log.Error("Failed to read input", zap.Error(err))
+		return 2
+	}
+	if !slices.Contains(convert.Formats, *from) {
+		log.Error("Specify the input format with -from", zap.String("from", *from))
+		return 2
+	}
+
+	res, err := convert.Convert(src, *from, *to)
+	if err != nil {
+		log.Error("Convert failed", zap.Error(err))
+		return 1
+	}
+
+	if *out == "" {
+		os.Stdout.Write(res)
+		return 0
+	}
+	if err := os.WriteFile(*out, res, 0o644); err != nil {
+		log.Error("Failed to write file", zap.Error(err))
+		return 2
+	}
+	return 0
+}
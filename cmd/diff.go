@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// unifiedDiff returns the changes from a to b in unified format.
+func unifiedDiff(name string, a, b []byte) []byte {
+	x, y := lines(a), lines(b)
+
+	d := differ{
+		a: x, b: y,
+		del: make([]bool, len(x)),
+		ins: make([]bool, len(y)),
+	}
+	d.compare(0, len(x), 0, len(y))
+
+	type op struct {
+		kind byte
+		text string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && d.del[i]:
+			ops = append(ops, op{'-', x[i]})
+			i++
+		case j < len(y) && d.ins[j]:
+			ops = append(ops, op{'+', y[j]})
+			j++
+		default:
+			ops = append(ops, op{' ', x[i]})
+			i, j = i+1, j+1
+		}
+	}
+
+	var dst bytes.Buffer
+	fmt.Fprintf(&dst, "--- %s.orig\n+++ %s\n", name, name)
+
+	aLine, bLine := 1, 1
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			aLine, bLine, k = aLine+1, bLine+1, k+1
+			continue
+		}
+
+		// Grow the hunk until a run of unchanged lines is long enough
+		// to separate it from the next change.
+		start := max(k-diffContext, 0)
+		end, same := k, 0
+		for end < len(ops) && same <= 2*diffContext {
+			if ops[end].kind == ' ' {
+				same++
+			} else {
+				same = 0
+			}
+			end++
+		}
+		end -= max(same-diffContext, 0)
+
+		aStart, bStart := aLine-(k-start), bLine-(k-start)
+		aN, bN := 0, 0
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				aN++
+			}
+			if o.kind != '-' {
+				bN++
+			}
+		}
+		fmt.Fprintf(&dst, "@@ -%s +%s @@\n", hunkRange(aStart, aN), hunkRange(bStart, bN))
+		for _, o := range ops[start:end] {
+			dst.WriteByte(o.kind)
+			dst.WriteString(o.text)
+			dst.WriteByte('\n')
+		}
+
+		for _, o := range ops[k:end] {
+			if o.kind != '+' {
+				aLine++
+			}
+			if o.kind != '-' {
+				bLine++
+			}
+		}
+		k = end
+	}
+
+	return dst.Bytes()
+}
+
+// differ finds the lines to delete from a and insert from b with the
+// linear space variant of Myers' O(ND) algorithm, so that files differing
+// in a few lines are compared in about linear time.
+type differ struct {
+	a, b     []string
+	del, ins []bool
+	vf, vb   []int
+}
+
+// compare marks the changes between a[aLo:aHi] and b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo, bLo = aLo+1, bLo+1
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi, bHi = aHi-1, bHi-1
+	}
+
+	switch {
+	case aLo == aHi:
+		for j := bLo; j < bHi; j++ {
+			d.ins[j] = true
+		}
+	case bLo == bHi:
+		for i := aLo; i < aHi; i++ {
+			d.del[i] = true
+		}
+	default:
+		x, y := d.split(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+}
+
+// split returns a point on a shortest edit path between a[aLo:aHi] and
+// b[bLo:bHi], found where the paths searched from both ends meet. The
+// ranges must differ in their first and last lines, so the point is
+// never one of the ends.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (int, int) {
+	a, b := d.a[aLo:aHi], d.b[bLo:bHi]
+	n, m := len(a), len(b)
+	delta, off := n-m, 2*(n+m+1)
+	if size := 2*off + 1; len(d.vf) < size {
+		d.vf, d.vb = make([]int, size), make([]int, size)
+	}
+	vf, vb := d.vf, d.vb
+	vf[off+1], vb[off+delta-1] = 0, n
+
+	for D := 0; ; D++ {
+		for k := -D; k <= D; k += 2 {
+			x := vf[off+k-1] + 1
+			if k == -D || k != D && vf[off+k-1] < vf[off+k+1] {
+				x = vf[off+k+1]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			vf[off+k] = x
+			if delta%2 != 0 && k >= delta-(D-1) && k <= delta+(D-1) && x >= vb[off+k] {
+				return aLo + x, bLo + y
+			}
+		}
+		for k := delta - D; k <= delta+D; k += 2 {
+			x := vb[off+k+1] - 1
+			if k == delta+D || k != delta-D && vb[off+k-1] < vb[off+k+1]-1 {
+				x = vb[off+k-1]
+			}
+			y := x - k
+			for x > 0 && y > 0 && a[x-1] == b[y-1] {
+				x, y = x-1, y-1
+			}
+			vb[off+k] = x
+			if delta%2 == 0 && k >= -D && k <= D && x <= vf[off+k] {
+				return aLo + x, bLo + y
+			}
+		}
+	}
+}
+
+// hunkRange formats a hunk's start and length the way diff -u does.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// lines splits b into lines. A last line without a newline carries the
+// marker diff prints for it, so it differs from the same line ended.
+func lines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	res := strings.Split(string(bytes.TrimSuffix(b, []byte("\n"))), "\n")
+	if b[len(b)-1] != '\n' {
+		res[len(res)-1] += noNewline
+	}
+	return res
+}
+
+const noNewline = "\n\\ No newline at end of file"
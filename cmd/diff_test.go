@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	seq := func(n int, repl map[int]string) string {
+		var sb strings.Builder
+		for i := 1; i <= n; i++ {
+			if s, ok := repl[i]; ok {
+				sb.WriteString(s + "\n")
+			} else {
+				sb.WriteString(strconv.Itoa(i) + "\n")
+			}
+		}
+		return sb.String()
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+		exp  string
+	}{
+		{
+			"one hunk",
+			seq(12, nil), seq(12, map[int]string{6: "six"}),
+			"@@ -3,7 +3,7 @@\n 3\n 4\n 5\n-6\n+six\n 7\n 8\n 9\n",
+		},
+		{
+			"merged hunks",
+			seq(20, nil), seq(20, map[int]string{4: "four", 11: "eleven"}),
+			"@@ -1,14 +1,14 @@\n 1\n 2\n 3\n-4\n+four\n 5\n 6\n 7\n 8\n 9\n 10\n-11\n+eleven\n 12\n 13\n 14\n",
+		},
+		{
+			"separate hunks",
+			seq(20, nil), seq(20, map[int]string{4: "four", 12: "twelve"}),
+			"@@ -1,7 +1,7 @@\n 1\n 2\n 3\n-4\n+four\n 5\n 6\n 7\n" +
+				"@@ -9,7 +9,7 @@\n 9\n 10\n 11\n-12\n+twelve\n 13\n 14\n 15\n",
+		},
+		{
+			"insert only",
+			"a\n", "a\nb\n",
+			"@@ -1 +1,2 @@\n a\n+b\n",
+		},
+		{
+			"from empty",
+			"", "a\n",
+			"@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			"missing newline added",
+			"a\nb", "a\nb\n",
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			"missing newline removed",
+			"a\nb\n", "a\nb",
+			"@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(unifiedDiff("f", []byte(tt.a), []byte(tt.b)))
+			exp := "--- f.orig\n+++ f\n" + tt.exp
+			if got != exp {
+				t.Errorf("expected\n%s\ngot\n%s", exp, got)
+			}
+		})
+	}
+}
+
+func BenchmarkUnifiedDiff(b *testing.B) {
+	var x, y strings.Builder
+	for i := range 20000 {
+		line := "KEY" + strconv.Itoa(i) + ": value\n"
+		x.WriteString(line)
+		if i%1000 == 0 {
+			y.WriteString("  " + line)
+		} else {
+			y.WriteString(line)
+		}
+	}
+	a, c := []byte(x.String()), []byte(y.String())
+
+	for b.Loop() {
+		unifiedDiff("f", a, c)
+	}
+}
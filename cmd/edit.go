@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/Votline/Gurlf/pkg/ast"
+)
+
+// runGet prints the value of KEY in the first section called SECTION,
+// exactly as Unmarshal would see it.
+func runGet(log *zap.Logger, args []string) int {
+	if len(args) != 3 {
+		log.Error("Usage: get FILE SECTION KEY")
+		return 2
+	}
+
+	f, ok := parseFile(log, args[0])
+	if !ok {
+		return 2
+	}
+	sec := f.Section(args[1])
+	if sec == nil {
+		log.Error("Section not found", zap.String("section", args[1]))
+		return 1
+	}
+	e := sec.Entry(args[2])
+	if e == nil {
+		log.Error("Key not found", zap.String("section", args[1]), zap.String("key", args[2]))
+		return 1
+	}
+
+	v := e.Value()
+	os.Stdout.Write(v)
+	if !bytes.HasSuffix(v, []byte("\n")) {
+		os.Stdout.WriteString("\n")
+	}
+	return 0
+}
+
+// runSet changes or adds KEY in SECTION, creating the section when it
+// does not exist. A VALUE of "-" is read from stdin.
+func runSet(log *zap.Logger, args []string) int {
+	if len(args) != 4 {
+		log.Error("Usage: set FILE SECTION KEY VALUE")
+		return 2
+	}
+
+	f, ok := parseFile(log, args[0])
+	if !ok {
+		return 2
+	}
+
+	v := args[3]
+	if v == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Error("Failed to read stdin", zap.Error(err))
+			return 2
+		}
+		v = string(b)
+	}
+
+	sec := f.Section(args[1])
+	if sec == nil {
+		var err error
+		if sec, err = f.AddSection(args[1]); err != nil {
+			log.Error("Failed to add section", zap.Error(err))
+			return 2
+		}
+	}
+	if err := sec.Set(args[2], v); err != nil {
+		log.Error("Failed to set key", zap.Error(err))
+		return 2
+	}
+
+	return writeFile(log, args[0], f)
+}
+
+// runDel removes KEY from SECTION or, without KEY, the whole section.
+func runDel(log *zap.Logger, args []string) int {
+	if len(args) != 2 && len(args) != 3 {
+		log.Error("Usage: del FILE SECTION [KEY]")
+		return 2
+	}
+
+	f, ok := parseFile(log, args[0])
+	if !ok {
+		return 2
+	}
+
+	if len(args) == 2 {
+		if !f.RemoveSection(args[1]) {
+			log.Error("Section not found", zap.String("section", args[1]))
+			return 1
+		}
+		return writeFile(log, args[0], f)
+	}
+
+	sec := f.Section(args[1])
+	if sec == nil {
+		log.Error("Section not found", zap.String("section", args[1]))
+		return 1
+	}
+	if !sec.Delete(args[2]) {
+		log.Error("Key not found", zap.String("section", args[1]), zap.String("key", args[2]))
+		return 1
+	}
+	return writeFile(log, args[0], f)
+}
+
+func parseFile(log *zap.Logger, p string) (*ast.File, bool) {
+	src, err := os.ReadFile(p)
+	if err != nil {
+		log.Error("Failed to read file", zap.Error(err))
+		return nil, false
+	}
+
+	f, err := ast.Parse(src)
+	if err != nil {
+		log.Error("Parse failed", zap.String("file", p), zap.Error(err))
+		return nil, false
+	}
+	return f, true
+}
+
+func writeFile(log *zap.Logger, p string, f *ast.File) int {
+	if err := os.WriteFile(p, f.Bytes(), 0o644); err != nil {
+		log.Error("Failed to write file", zap.Error(err))
+		return 2
+	}
+	return 0
+}
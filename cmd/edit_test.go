@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	const src = "[a]\nID: 1\n[\\a]\n"
+	tests := []struct {
+		name    string
+		section string
+		key     string
+		code    int
+		file    string
+	}{
+		{"change", "a", "ID", 0, "[a]\nID: 2\n[\\a]\n"},
+		{"add key", "a", "N", 0, "[a]\nID: 1\nN: 2\n[\\a]\n"},
+		{"add section", "b", "ID", 0, src + "\n[b]\nID: 2\n[\\b]\n"},
+		{"key with colon", "a", "A:B", 2, src},
+		{"comment key", "a", "#ID", 2, src},
+		{"empty key", "a", "", 2, src},
+		{"section with bracket", "b]", "ID", 2, src},
+		{"closing section", "\\b", "ID", 2, src},
+		{"empty section", "", "ID", 2, src},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tempFile(t, "f.gurlf", src)
+			if _, code := run(t, runSet, p, tt.section, tt.key, "2"); code != tt.code {
+				t.Errorf("expected exit code %d, got %d", tt.code, code)
+			}
+			if b, _ := os.ReadFile(p); string(b) != tt.file {
+				t.Errorf("expected file %q, got %q", tt.file, b)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	p := tempFile(t, "f.gurlf", "[a]\nID: 1\nBODY: ``\n{\n  \"k\": `v`\n}\n``\n[\\a]\n")
+
+	tests := []struct {
+		key  string
+		code int
+		out  string
+	}{
+		{"ID", 0, "1\n"},
+		{"BODY", 0, "{\n  \"k\": `v`\n}\n"},
+		{"NOPE", 1, ""},
+	}
+	for _, tt := range tests {
+		out, code := run(t, runGet, p, "a", tt.key)
+		if code != tt.code || out != tt.out {
+			t.Errorf("%s: expected %q and exit code %d, got %q and %d", tt.key, tt.out, tt.code, out, code)
+		}
+	}
+	if _, code := run(t, runGet, p, "b", "ID"); code != 1 {
+		t.Errorf("expected exit code 1 for a missing section, got %d", code)
+	}
+}
+
+func TestDel(t *testing.T) {
+	const src = "# top\n[a]\n  ID: 1\n# keep\nNAME:   dev\n[\\a]\n\n[b]\nK: `v`\n[\\b]\n"
+	tests := []struct {
+		name string
+		args []string
+		code int
+		file string
+	}{
+		{"key", []string{"a", "ID"}, 0, "# top\n[a]\n# keep\nNAME:   dev\n[\\a]\n\n[b]\nK: `v`\n[\\b]\n"},
+		{"section", []string{"b"}, 0, "# top\n[a]\n  ID: 1\n# keep\nNAME:   dev\n[\\a]\n"},
+		{"missing key", []string{"a", "NOPE"}, 1, src},
+		{"missing section", []string{"c"}, 1, src},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tempFile(t, "f.gurlf", src)
+			if _, code := run(t, runDel, append([]string{p}, tt.args...)...); code != tt.code {
+				t.Errorf("expected exit code %d, got %d", tt.code, code)
+			}
+			if b, _ := os.ReadFile(p); string(b) != tt.file {
+				t.Errorf("expected file %q, got %q", tt.file, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/Votline/Gurlf/pkg/ast"
+)
+
+// runFmt formats files in place or to stdout. In check mode (-l or -d
+// without -w) it exits with 1 when any file is not formatted; read or
+// syntax errors exit with 2.
+func runFmt(log *zap.Logger, args []string) int {
+	fl := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := fl.Bool("w", false, "write result to the source file instead of stdout")
+	list := fl.Bool("l", false, "list files whose formatting differs")
+	diff := fl.Bool("d", false, "display diffs instead of rewriting files")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+
+	if fl.NArg() == 0 {
+		if *write {
+			log.Error("Cannot use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Error("Failed to read stdin", zap.Error(err))
+			return 2
+		}
+		return formatFile(log, "<stdin>", src, false, *list, *diff)
+	}
+
+	code := 0
+	for _, p := range fl.Args() {
+		files, err := gurlfFiles(p)
+		if err != nil {
+			log.Error("Failed to list files", zap.String("path", p), zap.Error(err))
+			code = 2
+			continue
+		}
+
+		for _, f := range files {
+			src, err := os.ReadFile(f)
+			if err != nil {
+				log.Error("Failed to read file", zap.Error(err))
+				code = 2
+				continue
+			}
+			code = max(code, formatFile(log, f, src, *write, *list, *diff))
+		}
+	}
+	return code
+}
+
+func formatFile(log *zap.Logger, name string, src []byte, write, list, diff bool) int {
+	log.Debug("Formatting", zap.String("file", name))
+
+	res, err := ast.Format(src)
+	if err != nil {
+		log.Error("Format failed", zap.String("file", name), zap.Error(err))
+		return 2
+	}
+
+	changed := !bytes.Equal(src, res)
+	if list && changed {
+		os.Stdout.WriteString(name + "\n")
+	}
+	if diff && changed {
+		os.Stdout.Write(unifiedDiff(name, src, res))
+	}
+
+	switch {
+	case write:
+		if changed {
+			if err := os.WriteFile(name, res, 0o644); err != nil {
+				log.Error("Failed to write file", zap.Error(err))
+				return 2
+			}
+		}
+	case list || diff:
+		if changed {
+			return 1
+		}
+	default:
+		os.Stdout.Write(res)
+	}
+	return 0
+}
+
+// gurlfFiles returns p itself or, for a directory, every .gurlf file in it.
+func gurlfFiles(p string) ([]string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{p}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".gurlf" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
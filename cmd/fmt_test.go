@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFmtExitCodes(t *testing.T) {
+	const (
+		formatted   = "[a]\nID: 1\n[\\a]\n"
+		unformatted = "[a]\n  ID:    1\n[\\a]\n"
+		broken      = "[a]\nID: 1\n"
+	)
+	tests := []struct {
+		name string
+		flag string
+		src  string
+		code int
+		out  string
+		file string
+	}{
+		{"list formatted", "-l", formatted, 0, "", formatted},
+		{"list unformatted", "-l", unformatted, 1, "$F\n", unformatted},
+		{"list broken", "-l", broken, 2, "", broken},
+		{"diff formatted", "-d", formatted, 0, "", formatted},
+		{"diff unformatted", "-d", unformatted, 1,
+			"--- $F.orig\n+++ $F\n@@ -1,3 +1,3 @@\n [a]\n-  ID:    1\n+ID: 1\n [\\a]\n", unformatted},
+		{"diff broken", "-d", broken, 2, "", broken},
+		{"write formatted", "-w", formatted, 0, "", formatted},
+		{"write unformatted", "-w", unformatted, 0, "", formatted},
+		{"write broken", "-w", broken, 2, "", broken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tempFile(t, "f.gurlf", tt.src)
+			out, code := run(t, runFmt, tt.flag, p)
+			if code != tt.code {
+				t.Errorf("expected exit code %d, got %d", tt.code, code)
+			}
+			if exp := os.Expand(tt.out, func(string) string { return p }); out != exp {
+				t.Errorf("expected output %q, got %q", exp, out)
+			}
+			if b, _ := os.ReadFile(p); string(b) != tt.file {
+				t.Errorf("expected file %q, got %q", tt.file, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,774 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type pkgInfo struct {
+	name    string
+	test    bool
+	specs   map[string]*ast.TypeSpec
+	methods map[string][]string
+}
+
+// parseDir collects the type declarations of the package in dir that
+// declares types[0]. Test files are included, so types may live in them.
+func parseDir(dir string, types []string) (*pkgInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make(map[string]*pkgInfo)
+	var target *pkgInfo
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, e.Name()), nil,
+			parser.SkipObjectResolution|parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		// Methods from an earlier run must not be taken for custom codecs.
+		if ast.IsGenerated(f) && strings.Contains(f.Comments[0].Text(), "gurlfgen") {
+			continue
+		}
+
+		pkg := pkgs[f.Name.Name]
+		if pkg == nil {
+			pkg = &pkgInfo{
+				name:    f.Name.Name,
+				specs:   make(map[string]*ast.TypeSpec),
+				methods: make(map[string][]string),
+			}
+			pkgs[f.Name.Name] = pkg
+		}
+
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, s := range decl.Specs {
+					if ts, ok := s.(*ast.TypeSpec); ok {
+						pkg.specs[ts.Name.Name] = ts
+						if ts.Name.Name == types[0] {
+							target = pkg
+							target.test = strings.HasSuffix(e.Name(), "_test.go")
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv != nil && len(decl.Recv.List) == 1 {
+					if name := recvName(decl.Recv.List[0].Type); name != "" {
+						pkg.methods[name] = append(pkg.methods[name], decl.Name.Name)
+					}
+				}
+			}
+		}
+	}
+
+	if target == nil {
+		return nil, fmt.Errorf("type %s not found in %s", types[0], dir)
+	}
+	return target, nil
+}
+
+func recvName(e ast.Expr) string {
+	if s, ok := e.(*ast.StarExpr); ok {
+		e = s.X
+	}
+	if id, ok := e.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+type kind uint8
+
+const (
+	kString kind = iota
+	kBytes
+	kBool
+	kInt
+	kUint
+	kFloat
+	kComplex
+	kDuration
+)
+
+type value struct {
+	kind kind
+	bits int
+	typ  string
+}
+
+var basics = map[string]value{
+	"string":     {kString, 0, "string"},
+	"bool":       {kBool, 0, "bool"},
+	"int":        {kInt, 0, "int"},
+	"int8":       {kInt, 8, "int8"},
+	"int16":      {kInt, 16, "int16"},
+	"int32":      {kInt, 32, "int32"},
+	"rune":       {kInt, 32, "rune"},
+	"int64":      {kInt, 64, "int64"},
+	"uint":       {kUint, 0, "uint"},
+	"uint8":      {kUint, 8, "uint8"},
+	"byte":       {kUint, 8, "byte"},
+	"uint16":     {kUint, 16, "uint16"},
+	"uint32":     {kUint, 32, "uint32"},
+	"uint64":     {kUint, 64, "uint64"},
+	"uintptr":    {kUint, 0, "uintptr"},
+	"float32":    {kFloat, 32, "float32"},
+	"float64":    {kFloat, 64, "float64"},
+	"complex64":  {kComplex, 64, "complex64"},
+	"complex128": {kComplex, 128, "complex128"},
+}
+
+var codecMethods = []string{"UnmarshalGurlf", "MarshalGurlf", "UnmarshalText", "MarshalText"}
+
+type field struct {
+	path      string
+	tag       string
+	omitempty bool
+	required  bool
+	def       *string
+	name      bool
+
+	val  value
+	ptr  bool
+	list bool
+	// sec is the struct type of a nested section field.
+	sec string
+}
+
+type generator struct {
+	pkg     *pkgInfo
+	types   map[string]bool
+	imports map[string]bool
+	buf     bytes.Buffer
+}
+
+func generate(pkg *pkgInfo, types []string) ([]byte, error) {
+	g := &generator{
+		pkg:     pkg,
+		types:   make(map[string]bool),
+		imports: map[string]bool{"github.com/Votline/Gurlf/pkg/scanner": true},
+	}
+	for _, t := range types {
+		g.types[t] = true
+	}
+
+	var body bytes.Buffer
+	for _, t := range types {
+		fields, err := g.collect(t, "")
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %w", t, err)
+		}
+		g.buf.Reset()
+		g.unmarshal(t, fields)
+		g.append(t, fields)
+		body.Write(g.buf.Bytes())
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by gurlfgen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg.name)
+	var std, mod []string
+	for imp := range g.imports {
+		if strings.Contains(imp, ".") {
+			mod = append(mod, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	slices.Sort(std)
+	slices.Sort(mod)
+	for _, imp := range std {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString("\n")
+	for _, imp := range mod {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+// collect lists the tagged fields of the struct t in declaration order,
+// flattening embedded structs the way core does.
+func (g *generator) collect(t, prefix string) ([]field, error) {
+	ts, ok := g.pkg.specs[t]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", t)
+	}
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", t)
+	}
+
+	var res []field
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			raw, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(raw).Get("gurlf")
+		}
+
+		if len(f.Names) == 0 {
+			id, ok := f.Type.(*ast.Ident)
+			if !ok || g.pkg.specs[id.Name] == nil {
+				continue
+			}
+			if _, ok := g.pkg.specs[id.Name].Type.(*ast.StructType); !ok {
+				continue
+			}
+			inner, err := g.collect(id.Name, prefix+id.Name+".")
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, inner...)
+			continue
+		}
+
+		if tag == "" {
+			continue
+		}
+		for _, n := range f.Names {
+			fd, err := g.field(prefix+n.Name, tag, f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", n.Name, err)
+			}
+			res = append(res, fd)
+		}
+	}
+
+	return res, nil
+}
+
+func (g *generator) field(path, tag string, typ ast.Expr) (field, error) {
+	f := field{path: path}
+
+	name, rest, _ := strings.Cut(tag, ",")
+	f.tag = name
+	for rest != "" {
+		if def, ok := strings.CutPrefix(rest, "default="); ok {
+			f.def = &def
+			break
+		}
+
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+		switch opt {
+		case "omitempty":
+			f.omitempty = true
+		case "required":
+			f.required = true
+		case "remain":
+			return f, fmt.Errorf(`",remain" is not supported`)
+		}
+	}
+	if f.tag == "" {
+		return f, fmt.Errorf("empty key")
+	}
+
+	if s, ok := typ.(*ast.StarExpr); ok {
+		typ, f.ptr = s.X, true
+	} else if a, ok := typ.(*ast.ArrayType); ok && a.Len == nil && !g.isBytes(a) {
+		typ, f.list = a.Elt, true
+		if s, ok := typ.(*ast.StarExpr); ok {
+			typ, f.ptr = s.X, true
+		}
+	}
+
+	if sec := g.section(typ); sec != "" {
+		if f.tag == "config_name" {
+			return f, fmt.Errorf("config_name must not be a section")
+		}
+		if !g.types[sec] {
+			return f, fmt.Errorf("nested section type %s must be listed in -type", sec)
+		}
+		f.sec = sec
+		return f, nil
+	}
+
+	v, err := g.value(typ)
+	if err != nil {
+		return f, err
+	}
+	f.val = v
+	f.name = f.tag == "config_name"
+	if f.name && (f.ptr || f.list) {
+		return f, fmt.Errorf("config_name must be a plain value")
+	}
+
+	return f, nil
+}
+
+func (g *generator) isBytes(a *ast.ArrayType) bool {
+	id, ok := a.Elt.(*ast.Ident)
+	return ok && (id.Name == "byte" || id.Name == "uint8") && g.pkg.specs[id.Name] == nil
+}
+
+// section returns the name of the local struct type e refers to.
+func (g *generator) section(e ast.Expr) string {
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	ts := g.pkg.specs[id.Name]
+	if ts == nil || g.hasCodec(id.Name) {
+		return ""
+	}
+	if _, ok := ts.Type.(*ast.StructType); !ok {
+		return ""
+	}
+	return id.Name
+}
+
+func (g *generator) hasCodec(t string) bool {
+	for _, m := range g.pkg.methods[t] {
+		if slices.Contains(codecMethods, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) value(e ast.Expr) (value, error) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		ts := g.pkg.specs[e.Name]
+		if ts == nil {
+			if v, ok := basics[e.Name]; ok {
+				return v, nil
+			}
+			return value{}, fmt.Errorf("unsupported type %s", e.Name)
+		}
+		if g.hasCodec(e.Name) {
+			return value{}, fmt.Errorf("type %s has its own codec", e.Name)
+		}
+
+		var v value
+		if a, ok := ts.Type.(*ast.ArrayType); ok && a.Len == nil && g.isBytes(a) {
+			v = value{kind: kBytes}
+		} else {
+			var err error
+			if v, err = g.value(ts.Type); err != nil {
+				return value{}, err
+			}
+		}
+		v.typ = e.Name
+		return v, nil
+	case *ast.ArrayType:
+		if e.Len == nil && g.isBytes(e) {
+			return value{kBytes, 0, "[]byte"}, nil
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "time" && e.Sel.Name == "Duration" {
+			g.imports["time"] = true
+			return value{kDuration, 0, "time.Duration"}, nil
+		}
+	}
+
+	var b bytes.Buffer
+	format.Node(&b, token.NewFileSet(), e)
+	return value{}, fmt.Errorf("unsupported type %s", b.String())
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// tracked reports whether f needs a flag recording that its key was seen.
+func tracked(f field) bool {
+	return f.list || f.sec == "" && (f.required || f.def != nil)
+}
+
+func (g *generator) unmarshal(t string, fields []field) {
+	g.printf("\nfunc (*%s) GurlfGenerated() {}\n", t)
+	g.printf("\nfunc (x *%s) UnmarshalGurlf(d scanner.Data) error {\n", t)
+	g.printf("const op = %q\n\n", t+".UnmarshalGurlf")
+
+	var keys, secs []string
+	byTag := make(map[string][]field)
+	needStr, needHas := false, false
+	for _, f := range fields {
+		if f.name {
+			g.imports["unsafe"] = true
+			g.assign(f, "d.Name", "unsafe.String(unsafe.SliceData(d.Name), len(d.Name))", "len(d.Name) > 0")
+			continue
+		}
+
+		id := "key:" + f.tag
+		if f.sec != "" {
+			id = "sec:" + f.tag
+		}
+		if _, ok := byTag[id]; !ok {
+			if f.sec != "" {
+				secs = append(secs, id)
+			} else {
+				keys = append(keys, id)
+			}
+		}
+		byTag[id] = append(byTag[id], f)
+
+		needStr = needStr || (f.sec == "" && f.val.kind != kBytes)
+		needHas = needHas || tracked(f)
+	}
+
+	if needHas {
+		for i, f := range fields {
+			if tracked(f) {
+				g.printf("var has%d bool\n", i)
+			}
+		}
+	}
+
+	if len(keys) > 0 {
+		g.printf("for _, ent := range d.Entries {\n")
+		g.printf("val := d.RawData[ent.ValStart:ent.ValEnd]\n")
+		if needStr {
+			g.imports["unsafe"] = true
+			g.printf("str := unsafe.String(unsafe.SliceData(val), len(val))\n")
+		}
+		g.printf("switch string(d.RawData[ent.KeyStart:ent.KeyEnd]) {\n")
+		for _, id := range keys {
+			g.printf("case %q:\n", byTag[id][0].tag)
+			// Fields sharing a key get a block each, so that their
+			// temporaries do not clash.
+			shared := len(byTag[id]) > 1
+			for _, f := range byTag[id] {
+				i := slices.IndexFunc(fields, func(o field) bool { return o.path == f.path })
+				if shared {
+					g.printf("{\n")
+				}
+				if f.list {
+					g.printf("if !has%d {\nx.%s = x.%s[:0]\n}\n", i, f.path, f.path)
+				}
+				if tracked(f) {
+					g.printf("has%d = true\n", i)
+				}
+				g.assign(f, "val", "str", "len(val) > 0")
+				if shared {
+					g.printf("}\n")
+				}
+			}
+		}
+		g.printf("}\n}\n")
+	}
+
+	for i, f := range fields {
+		if f.sec != "" || !(f.required || f.def != nil) {
+			continue
+		}
+		g.printf("if !has%d {\n", i)
+		if f.required {
+			g.imports["fmt"] = true
+			g.imports["github.com/Votline/Gurlf/pkg/core"] = true
+			g.printf("return fmt.Errorf(\"%%s: %%w\", op, &core.KeyError{Section: string(d.Name), Key: %q, Line: d.Line, Err: core.ErrMissingKey})\n", f.tag)
+		} else {
+			if f.list {
+				g.printf("x.%s = x.%s[:0]\n", f.path, f.path)
+			}
+			cond := "false"
+			if *f.def != "" {
+				cond = ""
+			}
+			g.assign(f, fmt.Sprintf("[]byte(%q)", *f.def), strconv.Quote(*f.def), cond)
+		}
+		g.printf("}\n")
+	}
+
+	if len(secs) > 0 {
+		g.imports["fmt"] = true
+		g.printf("for _, ch := range d.Children {\n")
+		g.printf("switch string(ch.Name) {\n")
+		for _, id := range secs {
+			g.printf("case %q:\n", byTag[id][0].tag)
+			for _, f := range byTag[id] {
+				i := slices.IndexFunc(fields, func(o field) bool { return o.path == f.path })
+				target := "x." + f.path
+				switch {
+				case f.list:
+					g.printf("if !has%d {\nx.%s = x.%s[:0]\nhas%d = true\n}\n", i, f.path, f.path, i)
+					if f.ptr {
+						g.printf("x.%s = append(x.%s, new(%s))\n", f.path, f.path, f.sec)
+					} else {
+						g.printf("x.%s = append(x.%s, %s{})\n", f.path, f.path, f.sec)
+					}
+					target = fmt.Sprintf("x.%s[len(x.%s)-1]", f.path, f.path)
+				case f.ptr:
+					g.printf("if x.%s == nil {\nx.%s = new(%s)\n}\n", f.path, f.path, f.sec)
+				}
+				g.printf("if err := %s.UnmarshalGurlf(ch); err != nil {\n", target)
+				g.printf("return fmt.Errorf(\"%%s: section %%q: %%w\", op, ch.Name, err)\n}\n")
+			}
+		}
+		g.printf("}\n}\n")
+	}
+
+	g.printf("\nreturn nil\n}\n")
+}
+
+// assign writes the statements storing the value in valE/strE into f.
+// cond guards the conversion; "" means always and "false" never. Without
+// a guard the caller provides the enclosing block.
+func (g *generator) assign(f field, valE, strE, cond string) {
+	guarded := cond != "" && cond != "false"
+	begin := func() {
+		if guarded {
+			g.printf("if %s {\n", cond)
+		}
+	}
+	end := func() {
+		if guarded {
+			g.printf("}\n")
+		}
+	}
+	if guarded && f.list {
+		g.printf("{\n")
+	}
+
+	switch {
+	case f.list && f.ptr:
+		g.printf("var p *%s\n", f.val.typ)
+		if cond != "false" {
+			begin()
+			g.printf("var v %s\n", f.val.typ)
+			g.parse(f, "v", valE, strE)
+			g.printf("p = &v\n")
+			end()
+		}
+		g.printf("x.%s = append(x.%s, p)\n", f.path, f.path)
+	case f.list:
+		g.printf("var v %s\n", f.val.typ)
+		if cond != "false" {
+			begin()
+			g.parse(f, "v", valE, strE)
+			end()
+		}
+		g.printf("x.%s = append(x.%s, v)\n", f.path, f.path)
+	case cond == "false":
+	case f.ptr:
+		begin()
+		g.printf("var v %s\n", f.val.typ)
+		g.parse(f, "v", valE, strE)
+		g.printf("x.%s = &v\n", f.path)
+		end()
+	default:
+		begin()
+		g.parse(f, "x."+f.path, valE, strE)
+		end()
+	}
+
+	if guarded && f.list {
+		g.printf("}\n")
+	}
+}
+
+// natural is the type each kind is parsed into before conversion.
+var natural = map[kind]string{
+	kString:   "string",
+	kBytes:    "[]byte",
+	kBool:     "bool",
+	kInt:      "int64",
+	kUint:     "uint64",
+	kFloat:    "float64",
+	kComplex:  "complex128",
+	kDuration: "time.Duration",
+}
+
+func convert(v value, e string) string {
+	if v.typ == natural[v.kind] {
+		return e
+	}
+	return v.typ + "(" + e + ")"
+}
+
+func (g *generator) parse(f field, dst, valE, strE string) {
+	v := f.val
+	var call string
+	switch v.kind {
+	case kString:
+		g.printf("%s = %s\n", dst, convert(v, strE))
+		return
+	case kBytes:
+		g.printf("%s = %s\n", dst, convert(v, valE))
+		return
+	case kBool:
+		call = fmt.Sprintf("strconv.ParseBool(%s)", strE)
+	case kInt:
+		call = fmt.Sprintf("strconv.ParseInt(%s, 10, %d)", strE, v.bits)
+	case kUint:
+		call = fmt.Sprintf("strconv.ParseUint(%s, 10, %d)", strE, v.bits)
+	case kFloat:
+		call = fmt.Sprintf("strconv.ParseFloat(%s, %d)", strE, v.bits)
+	case kComplex:
+		call = fmt.Sprintf("strconv.ParseComplex(%s, %d)", strE, v.bits)
+	case kDuration:
+		call = fmt.Sprintf("time.ParseDuration(%s)", strE)
+	}
+	if v.kind != kDuration {
+		g.imports["strconv"] = true
+	}
+	g.imports["fmt"] = true
+
+	g.printf("n, err := %s\n", call)
+	g.printf("if err != nil {\nreturn fmt.Errorf(\"%%s: %%s: %%w\", op, %q, err)\n}\n", f.tag)
+	g.printf("%s = %s\n", dst, convert(v, "n"))
+}
+
+func (g *generator) append(t string, fields []field) {
+	g.printf("\nfunc (x *%s) AppendGurlf(dst []byte) []byte {\n", t)
+
+	for _, f := range fields {
+		if f.name || f.sec != "" {
+			continue
+		}
+
+		expr := "x." + f.path
+		line := func(e string) {
+			g.printf("dst = append(dst, %q...)\n", f.tag+":")
+			if f.ptr {
+				g.printf("if %s != nil {\n", e)
+				g.appendValue(f.val, "(*"+e+")")
+				g.printf("}\n")
+			} else {
+				g.appendValue(f.val, e)
+			}
+			g.printf("dst = append(dst, '\\n')\n")
+		}
+
+		switch {
+		case f.list:
+			if f.omitempty {
+				g.printf("if %s != nil {\n", expr)
+			}
+			g.printf("for _, v := range %s {\n", expr)
+			line("v")
+			g.printf("}\n")
+			if f.omitempty {
+				g.printf("}\n")
+			}
+		case f.omitempty:
+			if f.ptr {
+				g.printf("if %s != nil {\n", expr)
+			} else {
+				g.printf("if %s {\n", g.nonZero(f.val, expr))
+			}
+			line(expr)
+			g.printf("}\n")
+		default:
+			line(expr)
+		}
+	}
+
+	for _, f := range fields {
+		if f.sec == "" {
+			continue
+		}
+
+		expr := "x." + f.path
+		section := func(e string) {
+			g.printf("dst = append(dst, %q...)\n", "["+f.tag+"]\n")
+			g.printf("dst = %s.AppendGurlf(dst)\n", e)
+			g.printf("dst = append(dst, %q...)\n", "[\\"+f.tag+"]\n")
+		}
+
+		switch {
+		case f.list && f.ptr:
+			g.printf("for _, s := range %s {\nif s != nil {\n", expr)
+			section("s")
+			g.printf("}\n}\n")
+		case f.list:
+			g.printf("for i := range %s {\n", expr)
+			section(expr + "[i]")
+			g.printf("}\n")
+		case f.ptr:
+			g.printf("if %s != nil {\n", expr)
+			section(expr)
+			g.printf("}\n")
+		default:
+			section(expr)
+		}
+	}
+
+	g.printf("\nreturn dst\n}\n")
+}
+
+func (g *generator) appendValue(v value, e string) {
+	switch v.kind {
+	case kString:
+		g.imports["github.com/Votline/Gurlf/pkg/core"] = true
+		g.printf("dst = core.AppendText(dst, string(%s))\n", e)
+	case kBytes:
+		g.imports["github.com/Votline/Gurlf/pkg/core"] = true
+		g.imports["unsafe"] = true
+		g.printf("dst = core.AppendText(dst, unsafe.String(unsafe.SliceData(%s), len(%s)))\n", e, e)
+	case kBool:
+		g.imports["strconv"] = true
+		g.printf("dst = strconv.AppendBool(dst, bool(%s))\n", e)
+	case kInt:
+		g.imports["strconv"] = true
+		g.printf("dst = strconv.AppendInt(dst, int64(%s), 10)\n", e)
+	case kUint:
+		g.imports["strconv"] = true
+		g.printf("dst = strconv.AppendUint(dst, uint64(%s), 10)\n", e)
+	case kFloat:
+		g.imports["strconv"] = true
+		g.printf("dst = strconv.AppendFloat(dst, float64(%s), 'f', -1, %d)\n", e, v.bits)
+	case kComplex:
+		g.imports["strconv"] = true
+		g.printf("dst = append(dst, strconv.FormatComplex(complex128(%s), 'f', -1, %d)...)\n", e, v.bits)
+	case kDuration:
+		g.printf("dst = append(dst, time.Duration(%s).String()...)\n", e)
+	}
+}
+
+// nonZero returns an expression that is true when e is not the zero value,
+// matching reflect.Value.IsZero.
+func (g *generator) nonZero(v value, e string) string {
+	switch v.kind {
+	case kString:
+		return e + ` != ""`
+	case kBytes:
+		return e + " != nil"
+	case kBool:
+		return "bool(" + e + ")"
+	case kFloat, kComplex:
+		g.imports["math"] = true
+		bits, conv := "math.Float64bits", "float64"
+		if v.bits == 32 || v.bits == 64 && v.kind == kComplex {
+			bits, conv = "math.Float32bits", "float32"
+		}
+		if v.kind == kFloat {
+			return fmt.Sprintf("%s(%s(%s)) != 0", bits, conv, e)
+		}
+		c := "complex128"
+		if conv == "float32" {
+			c = "complex64"
+		}
+		return fmt.Sprintf("%s(real(%s(%s))) != 0 || %s(imag(%s(%s))) != 0", bits, c, e, bits, c, e)
+	}
+	return e + " != 0"
+}
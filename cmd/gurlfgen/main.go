@@ -0,0 +1,60 @@
+// Gurlfgen generates reflection-free UnmarshalGurlf and AppendGurlf
+// methods for structs with gurlf tags. It is meant for go:generate:
+//
+//	//go:generate gurlfgen -type Config,Auth
+//
+// Every struct used as a nested section of a listed type must be listed
+// as well. Fields with custom codecs, maps and ",remain" are not supported;
+// such types keep using reflection.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	types := flag.String("type", "", "comma-separated list of struct names")
+	output := flag.String("output", "", "output file; default <type>_gurlf.go")
+	flag.Parse()
+
+	if *types == "" {
+		fmt.Fprintln(os.Stderr, "gurlfgen: -type is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, strings.Split(*types, ","), *output); err != nil {
+		fmt.Fprintf(os.Stderr, "gurlfgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, types []string, output string) error {
+	pkg, err := parseDir(dir, types)
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(pkg, types)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = strings.ToLower(types[0]) + "_gurlf.go"
+		if pkg.test {
+			output = strings.ToLower(types[0]) + "_gurlf_test.go"
+		}
+		output = filepath.Join(dir, output)
+	}
+	return os.WriteFile(output, src, 0o644)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Votline/Gurlf/pkg/httpreq"
+)
+
+// runImport turns a curl command line or a .http file into request
+// sections, printed or appended to -o.
+func runImport(log *zap.Logger, args []string) int {
+	fl := flag.NewFlagSet("import", flag.ContinueOnError)
+	name := fl.String("name", "request", "section name for a curl command")
+	out := fl.String("o", "", "append sections to this file instead of stdout")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+	if fl.NArg() > 1 {
+		log.Error("Usage: import [-name NAME] [-o FILE] [FILE]")
+		return 2
+	}
+
+	var src []byte
+	var err error
+	if fl.NArg() == 0 || fl.Arg(0) == "-" {
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		src, err = os.ReadFile(fl.Arg(0))
+	}
+	if err != nil {
+		log.Error("Failed to read input", zap.Error(err))
+		return 2
+	}
+
+	var reqs []httpreq.Request
+	if t := strings.TrimSpace(string(src)); strings.HasPrefix(t, "curl ") {
+		r, err := httpreq.ParseCurl(t)
+		if err != nil {
+			log.Error("Import failed", zap.Error(err))
+			return 1
+		}
+		r.Name = *name
+		reqs = append(reqs, r)
+	} else if reqs, err = httpreq.ParseHTTPFile(src); err != nil {
+		log.Error("Import failed", zap.Error(err))
+		return 1
+	}
+	log.Debug("Imported", zap.Int("requests", len(reqs)))
+
+	res, err := httpreq.Marshal(reqs)
+	if err != nil {
+		log.Error("Marshal failed", zap.Error(err))
+		return 1
+	}
+	if *out == "" {
+		os.Stdout.Write(res)
+		return 0
+	}
+
+	prev, err := os.ReadFile(*out)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Error("Failed to read file", zap.Error(err))
+		return 2
+	}
+	if len(bytes.TrimSpace(prev)) > 0 {
+		prev = append(bytes.TrimRight(prev, "\n"), "\n\n"...)
+		res = append(prev, res...)
+	}
+	if err := os.WriteFile(*out, res, 0o644); err != nil {
+		log.Error("Failed to write file", zap.Error(err))
+		return 2
+	}
+	return 0
+}
+
+// runExport prints the request sections of a file as curl commands.
+func runExport(log *zap.Logger, args []string) int {
+	fl := flag.NewFlagSet("export", flag.ContinueOnError)
+	section := fl.String("section", "", "export only the sections with this name")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+	if fl.NArg() != 1 {
+		log.Error("Usage: export [-section NAME] FILE")
+		return 2
+	}
+
+	src, err := os.ReadFile(fl.Arg(0))
+	if err != nil {
+		log.Error("Failed to read file", zap.Error(err))
+		return 2
+	}
+	reqs, err := httpreq.Parse(src)
+	if err != nil {
+		log.Error("Parse failed", zap.Error(err))
+		return 1
+	}
+
+	n := 0
+	for _, r := range reqs {
+		if *section != "" && r.Name != *section {
+			continue
+		}
+		if n > 0 {
+			os.Stdout.WriteString("\n")
+		}
+		os.Stdout.WriteString("# " + r.Name + "\n" + r.Curl() + "\n")
+		n++
+	}
+	if n == 0 {
+		log.Error("No requests found", zap.String("section", *section))
+		return 1
+	}
+	return 0
+}
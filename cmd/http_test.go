@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestImportName(t *testing.T) {
+	p := tempFile(t, "req.sh", "curl https://example.com/me\n")
+
+	out, code := run(t, runImport, "-name", "me", p)
+	if exp := "[me]\nURL:https://example.com/me\n[\\me]\n\n"; code != 0 || out != exp {
+		t.Errorf("expected %q and exit code 0, got %q and %d", exp, out, code)
+	}
+
+	for _, name := range []string{"", "me]", "\\me"} {
+		if out, code := run(t, runImport, "-name", name, p); code != 1 || out != "" {
+			t.Errorf("%q: expected no output and exit code 1, got %q and %d", name, out, code)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	p := tempFile(t, "api.gurlf", "[common]\nHOST: example.com\n[\\common]\n\n"+
+		"[me]\nURL: https://example.com/me\n[\\me]\n\n[logout]\nMETHOD: POST\nURL: https://example.com/logout\n[\\logout]\n")
+
+	out, code := run(t, runExport, p)
+	if exp := "# me\ncurl https://example.com/me\n\n# logout\ncurl -X POST https://example.com/logout\n"; code != 0 || out != exp {
+		t.Errorf("expected %q and exit code 0, got %q and %d", exp, out, code)
+	}
+	if out, code := run(t, runExport, "-section", "common", p); code != 1 || out != "" {
+		t.Errorf("expected no output and exit code 1 for a non-request section, got %q and %d", out, code)
+	}
+}
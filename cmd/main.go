@@ -7,10 +7,25 @@ import (
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-
-	"github.com/Votline/Gurlf"
 )
 
+type command struct {
+	name  string
+	usage string
+	run   func(log *zap.Logger, args []string) int
+}
+
+var commands = []command{
+	{"fmt", "fmt [-w] [-l] [-d] [path ...]", runFmt},
+	{"validate", "validate [-format text|json|sarif] path|glob ...", runValidate},
+	{"get", "get FILE SECTION KEY", runGet},
+	{"set", "set FILE SECTION KEY VALUE|-", runSet},
+	{"del", "del FILE SECTION [KEY]", runDel},
+	{"convert", "convert [-from FORMAT] [-to FORMAT] [-o FILE] [FILE]", runConvert},
+	{"import", "import [-name NAME] [-o FILE] [curl command or .http FILE]", runImport},
+	{"export", "export [-section NAME] FILE", runExport},
+}
+
 func initLogger(d *bool) *zap.Logger {
 	cfg := zap.NewDevelopmentConfig()
 	cfg.Encoding = "console"
@@ -30,39 +45,34 @@ func initLogger(d *bool) *zap.Logger {
 	return log
 }
 
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gurlf [-debug] <command> [arguments]\n\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", c.usage)
+	}
+}
+
 func main() {
 	debug := flag.Bool("debug", false, "use for debug mode")
+	flag.Usage = usage
 	flag.Parse()
 
 	log := initLogger(debug)
+	defer log.Sync()
 
 	args := flag.Args()
 	if len(args) < 1 {
-		log.Error("Specify the path to file")
-		return
-	}
-	p := args[0]
-
-	d, err := os.ReadFile(p)
-	if err != nil {
-		log.Fatal("Failed to read file", zap.Error(err))
+		usage()
+		os.Exit(2)
 	}
 
-	data, err := gurlf.Scan(d)
-	if err != nil {
-		log.Error("Scan failed", zap.Error(err))
-	}
-
-	s := struct {
-		ID   int    `gurlf:"ID"`
-		Body string `gurlf:"BODY"`
-		Hdrs string `gurlf:"HEADERS"`
-	}{}
-
-	err = gurlf.Unmarshal(data[1], &s)
-	if err != nil {
-		log.Error("Unmarshal failed", zap.Error(err))
+	for _, c := range commands {
+		if c.name == args[0] {
+			os.Exit(c.run(log, args[1:]))
+		}
 	}
 
-	fmt.Printf("\n%d\n%s\n%s\n", s.ID, s.Body, s.Hdrs)
+	log.Error("Unknown command", zap.String("command", args[0]))
+	usage()
+	os.Exit(2)
 }
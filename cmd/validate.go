@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+// diagnostic is a problem found in a file. Line and Col are 1-based and
+// zero when the position is unknown.
+type diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// runValidate checks the syntax of files, directories and glob patterns.
+// It exits with 1 when a problem was found and 2 when a file could not
+// be read.
+func runValidate(log *zap.Logger, args []string) int {
+	fl := flag.NewFlagSet("validate", flag.ContinueOnError)
+	format := fl.String("format", "text", "output format: text, json or sarif")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+	if fl.NArg() == 0 {
+		log.Error("Specify files to validate")
+		return 2
+	}
+
+	report, ok := reporters[*format]
+	if !ok {
+		log.Error("Unknown format", zap.String("format", *format))
+		return 2
+	}
+
+	code := 0
+	diags := []diagnostic{}
+	for _, pattern := range fl.Args() {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Error("Bad pattern", zap.String("pattern", pattern), zap.Error(err))
+			return 2
+		}
+		if paths == nil {
+			paths = []string{pattern}
+		}
+
+		for _, p := range paths {
+			files, err := gurlfFiles(p)
+			if err != nil {
+				log.Error("Failed to list files", zap.String("path", p), zap.Error(err))
+				code = 2
+				continue
+			}
+
+			for _, f := range files {
+				log.Debug("Validating", zap.String("file", f))
+				src, err := os.ReadFile(f)
+				if err != nil {
+					log.Error("Failed to read file", zap.Error(err))
+					code = 2
+					continue
+				}
+				diags = append(diags, validate(f, src)...)
+			}
+		}
+	}
+
+	if err := report(os.Stdout, diags); err != nil {
+		log.Error("Failed to write report", zap.Error(err))
+		return 2
+	}
+	if code == 0 && len(diags) > 0 {
+		code = 1
+	}
+	return code
+}
+
+func validate(name string, src []byte) []diagnostic {
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+
+	_, err := s.ScanRecover(src)
+	if err == nil {
+		return nil
+	}
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return []diagnostic{{File: name, Message: err.Error()}}
+	}
+
+	diags := make([]diagnostic, 0, len(list))
+	for _, se := range list {
+		msg := se.Kind.String()
+		if se.Section != "" {
+			msg += fmt.Sprintf(" in section %q", se.Section)
+		}
+		diags = append(diags, diagnostic{File: name, Line: se.Line, Col: se.Col, Message: msg})
+	}
+	return diags
+}
+
+var reporters = map[string]func(w io.Writer, diags []diagnostic) error{
+	"text":  reportText,
+	"json":  reportJSON,
+	"sarif": reportSARIF,
+}
+
+func reportText(w io.Writer, diags []diagnostic) error {
+	for _, d := range diags {
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s\n", d.File, d.Line, d.Col, d.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reportJSON(w io.Writer, diags []diagnostic) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diags)
+}
+
+// reportSARIF writes diags as a SARIF 2.1.0 log, the format code
+// scanning services accept.
+func reportSARIF(w io.Writer, diags []diagnostic) error {
+	type region struct {
+		StartLine   int `json:"startLine,omitempty"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region *region `json:"region,omitempty"`
+		} `json:"physicalLocation"`
+	}
+	type message struct {
+		Text string `json:"text"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     string     `json:"level"`
+		Message   message    `json:"message"`
+		Locations []location `json:"locations"`
+	}
+
+	results := make([]result, 0, len(diags))
+	for _, d := range diags {
+		var loc location
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(d.File)
+		if d.Line > 0 {
+			loc.PhysicalLocation.Region = &region{StartLine: d.Line, StartColumn: d.Col}
+		}
+		results = append(results, result{
+			RuleID:    "syntax",
+			Level:     "error",
+			Message:   message{d.Message},
+			Locations: []location{loc},
+		})
+	}
+
+	log := map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []any{map[string]any{
+			"tool": map[string]any{"driver": map[string]any{
+				"name":           "gurlf",
+				"informationUri": "https://github.com/Votline/Gurlf",
+				"rules": []any{map[string]any{
+					"id":               "syntax",
+					"shortDescription": message{"Malformed gurlf syntax"},
+				}},
+			}},
+			"results": results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
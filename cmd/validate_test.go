@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const invalid = "[a]\nID: 1\n[\\a]\n" +
+	"[b]\nID: 2\n" +
+	"[c]\nUser: dev[\\c]\n" +
+	"[d]\nID: 4\n[\\d]\n" +
+	"  [e\n"
+
+func TestValidateText(t *testing.T) {
+	p := tempFile(t, "bad.gurlf", invalid)
+
+	out, code := run(t, runValidate, p)
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	exp := p + ":4:1: unclosed section in section \"b\"\n" +
+		p + ":7:1: missing value end in section \"c\"\n" +
+		p + ":11:3: missing ']'\n"
+	if out != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, out)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	p := tempFile(t, "bad.gurlf", invalid)
+
+	out, code := run(t, runValidate, "-format", "json", p)
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	var got []diagnostic
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	exp := []diagnostic{
+		{p, 4, 1, "unclosed section in section \"b\""},
+		{p, 7, 1, "missing value end in section \"c\""},
+		{p, 11, 3, "missing ']'"},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %+v, got %+v", exp, got)
+	}
+
+	out, _ = run(t, runValidate, "-format", "json", tempFile(t, "ok.gurlf", "[a]\nID: 1\n[\\a]\n"))
+	if out != "[]\n" {
+		t.Errorf("expected an empty list, got %q", out)
+	}
+}
+
+func TestValidateSARIF(t *testing.T) {
+	p := tempFile(t, "bad.gurlf", invalid)
+
+	out, code := run(t, runValidate, "-format", "sarif", p)
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatalf("invalid SARIF %q: %v", out, err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("expected one SARIF 2.1.0 run, got %+v", log)
+	}
+	r := log.Runs[0]
+	if r.Tool.Driver.Name != "gurlf" || len(r.Tool.Driver.Rules) != 1 || r.Tool.Driver.Rules[0].ID != "syntax" {
+		t.Errorf("unexpected tool %+v", r.Tool)
+	}
+	if len(r.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(r.Results))
+	}
+	res := r.Results[1]
+	loc := res.Locations[0].PhysicalLocation
+	if res.RuleID != "syntax" || res.Level != "error" || res.Message.Text != "missing value end in section \"c\"" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if loc.ArtifactLocation.URI != filepath.ToSlash(p) || loc.Region.StartLine != 7 || loc.Region.StartColumn != 1 {
+		t.Errorf("expected %s:7:1, got %+v", p, loc)
+	}
+}
+
+func TestValidateExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"valid", []string{tempFile(t, "ok.gurlf", "[a]\nID: 1\n[\\a]\n")}, 0},
+		{"invalid", []string{tempFile(t, "bad.gurlf", invalid)}, 1},
+		{"empty dir", []string{dir}, 0},
+		{"missing file", []string{filepath.Join(dir, "missing.gurlf")}, 2},
+		{"no files", nil, 2},
+		{"unknown format", []string{"-format", "xml", dir}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, code := run(t, runValidate, tt.args...); code != tt.code {
+				t.Errorf("expected exit code %d, got %d", tt.code, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package gurlf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Votline/Gurlf/pkg/core"
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+const minRead = 4096
+
+// Decoder reads gurlf sections one at a time from an input stream.
+// Only the section being decoded and the unread tail of the last read
+// are held in memory.
+//
+// Strings decoded from a section share memory with the Decoder's buffer,
+// which is never overwritten once handed out.
+type Decoder struct {
+	r   io.Reader
+	s   *scanner.Scanner
+	buf []byte
+	err error
+
+	off, line, col int
+
+	opts core.UnmarshalOptions
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:    r,
+		s:    new(scanner.Scanner),
+		line: 1,
+		col:  1,
+	}
+}
+
+// DisallowUnknownKeys makes Decode fail on keys no field is tagged with.
+func (d *Decoder) DisallowUnknownKeys() {
+	d.opts.DisallowUnknownKeys = true
+}
+
+// DisallowDuplicateKeys makes Decode fail on keys repeated in a section.
+func (d *Decoder) DisallowDuplicateKeys() {
+	d.opts.DisallowDuplicateKeys = true
+}
+
+// Expand makes Decode replace ${...} references, asking r for those that
+// are not keys of the section. A nil r resolves ${env:NAME} only.
+// Sections already decoded are not kept, so ${section.KEY} references
+// to other sections are asked of r as well.
+func (d *Decoder) Expand(r core.Resolver) {
+	d.opts.Expand = true
+	d.opts.Resolver = r
+}
+
+// More reports whether there is another section to decode.
+func (d *Decoder) More() bool {
+	for {
+		d.discard(blank(d.buf))
+
+		if len(d.buf) > 0 && d.buf[0] != '#' {
+			return true
+		}
+		if d.err != nil {
+			return false
+		}
+		d.fill()
+	}
+}
+
+// Decode reads the next section and stores it in v, which is either
+// a pointer to a struct accepted by Unmarshal or a *scanner.Data.
+// It returns io.EOF when the input holds no more sections.
+func (d *Decoder) Decode(v any) error {
+	const op = "gurlf.Decoder.Decode"
+
+	for {
+		dt, n, err := d.s.Next(d.buf)
+		if err == nil && n > 0 {
+			off, line := d.off, d.line
+			d.discard(n)
+			return d.store(dt, off, line, v)
+		}
+
+		if err != nil && !incomplete(err) {
+			return fmt.Errorf("%s: %w", op, d.locate(err))
+		}
+		if d.err == nil {
+			d.fillUntil(closing(err))
+			continue
+		}
+		if d.err != io.EOF {
+			return fmt.Errorf("%s: read: %w", op, d.err)
+		}
+		if err == nil {
+			d.discard(len(d.buf))
+			return io.EOF
+		}
+
+		return fmt.Errorf("%s: %w", op, d.locate(err))
+	}
+}
+
+func (d *Decoder) store(dt scanner.Data, off, line int, v any) error {
+	const op = "gurlf.Decoder.Decode"
+
+	if p, ok := v.(*scanner.Data); ok {
+		ds := scanner.Detach([]scanner.Data{dt})
+		shift(ds, off, line)
+		*p = ds[0]
+		return nil
+	}
+
+	dt.Line += line - 1
+	shift(dt.Children, 0, line)
+	if err := d.opts.Unmarshal(dt, v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (d *Decoder) fill() {
+	if cap(d.buf)-len(d.buf) < minRead {
+		nb := make([]byte, len(d.buf), 2*len(d.buf)+minRead)
+		copy(nb, d.buf)
+		d.buf = nb
+	}
+
+	n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+	d.buf = d.buf[:len(d.buf)+n]
+	if err != nil {
+		d.err = err
+	}
+}
+
+// fillUntil reads until end can be in the buffer, so a section is not
+// scanned again for every read while it is incomplete. A nil end is
+// satisfied by any read.
+func (d *Decoder) fillUntil(end []byte) {
+	for {
+		from := max(len(d.buf)-len(end)+1, 0)
+		d.fill()
+		if end == nil || d.err != nil || bytes.Contains(d.buf[from:], end) {
+			return
+		}
+	}
+}
+
+func (d *Decoder) discard(n int) {
+	for _, c := range d.buf[:n] {
+		if c == '\n' {
+			d.line++
+			d.col = 1
+		} else {
+			d.col++
+		}
+	}
+	d.off += n
+	d.buf = d.buf[n:]
+}
+
+func (d *Decoder) locate(err error) error {
+	var se *scanner.SyntaxError
+	if errors.As(err, &se) {
+		if se.Line == 1 {
+			se.Col += d.col - 1
+		}
+		se.Line += d.line - 1
+		se.Offset += d.off
+	}
+	return err
+}
+
+func shift(ds []scanner.Data, off, line int) {
+	for i := range ds {
+		ds[i].Offset += off
+		ds[i].Line += line - 1
+		shift(ds[i].Children, off, line)
+	}
+}
+
+func incomplete(err error) bool {
+	var se *scanner.SyntaxError
+	if !errors.As(err, &se) {
+		return false
+	}
+
+	switch se.Kind {
+	case scanner.UnclosedSection, scanner.MissingBracket, scanner.MissingSectionStart:
+		return true
+	case scanner.UnterminatedBlock:
+		// The section end found may be a line of the block, whose
+		// fence has not been read yet.
+		return true
+	}
+	return false
+}
+
+// closing returns the line that ends the section err waits for, or nil
+// when any more input may complete it.
+func closing(err error) []byte {
+	var se *scanner.SyntaxError
+	if !errors.As(err, &se) || se.Section == "" {
+		return nil
+	}
+	switch se.Kind {
+	case scanner.UnclosedSection, scanner.UnterminatedBlock:
+		return []byte("[\\" + se.Section + "]")
+	}
+	return nil
+}
+
+// blank returns the length of the whitespace and complete comment lines
+// at the start of b.
+func blank(b []byte) int {
+	i := 0
+	for i < len(b) {
+		if isSpace(b[i]) {
+			i++
+		} else if b[i] != '#' {
+			break
+		} else if n := bytes.IndexByte(b[i:], '\n'); n != -1 {
+			i += n + 1
+		} else {
+			break
+		}
+	}
+	return i
+}
+
+func isSpace(r byte) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f'
+}
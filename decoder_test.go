@@ -0,0 +1,247 @@
+package gurlf
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+func TestDecoder(t *testing.T) {
+	input := `
+# requests: [first] and [second]
+[first]
+ID: 1
+BODY: ` + "`\n{ \"key\": \"value\" }\n`" + `
+[\first]
+
+[second]
+# ID: 3
+ID: 2
+BODY: plain
+[\second]
+# trailing`
+	type cfg struct {
+		Name string `gurlf:"config_name"`
+		ID   int    `gurlf:"ID"`
+		Body string `gurlf:"BODY"`
+	}
+	tests := []cfg{
+		{"first", 1, "\n{ \"key\": \"value\" }\n"},
+		{"second", 2, "plain"},
+	}
+
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	var got []cfg
+	for dec.More() {
+		var c cfg
+		if err := dec.Decode(&c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, c)
+	}
+	if err := dec.Decode(&cfg{}); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	if len(got) != len(tests) {
+		t.Fatalf("len mismatch: expected %d, got %d", len(tests), len(got))
+	}
+	for i, tt := range tests {
+		if got[i] != tt {
+			t.Errorf("[%d]: expected %+v, got %+v", i, tt, got[i])
+		}
+	}
+}
+
+func TestDecoderData(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("[a]\nID: 1\n[\\a]\n[b]\nID: 2\n[\\b]"))
+
+	var first, second scanner.Data
+	if err := dec.Decode(&first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := dec.Decode(&second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		d   scanner.Data
+		exp string
+	}{
+		{first, "1"},
+		{second, "2"},
+	}
+	for i, tt := range tests {
+		ent := tt.d.Entries[0]
+		if val := string(tt.d.RawData[ent.ValStart:ent.ValEnd]); val != tt.exp {
+			t.Errorf("[%d]: expected %q, got %q", i, tt.exp, val)
+		}
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	input := "[a]\nID: 1\n[\\a]\n\n[b]\nID: 2\n"
+	dec := NewDecoder(iotest.HalfReader(strings.NewReader(input)))
+
+	var d scanner.Data
+	if err := dec.Decode(&d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := dec.Decode(&d)
+	var se *scanner.SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *scanner.SyntaxError, got %v", err)
+	}
+	if se.Kind != scanner.UnclosedSection || se.Line != 5 || se.Col != 1 {
+		t.Errorf("expected unclosed section at 5:1, got %v", se)
+	}
+}
+
+func TestDecoderMalformedEntry(t *testing.T) {
+	input := "[a]\nID: 1\n[\\a]\n\n[b]\nBODY: `\n{}\n[\\b]\n"
+	dec := NewDecoder(strings.NewReader(input))
+
+	var d scanner.Data
+	if err := dec.Decode(&d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := dec.Decode(&d)
+	var se *scanner.SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *scanner.SyntaxError, got %v", err)
+	}
+	if se.Line != 6 {
+		t.Errorf("expected an error at line 6, got %v", se)
+	}
+}
+
+func TestDecoderBlockAcrossReads(t *testing.T) {
+	// The first [\b] is a line of the block; a read ending after it must
+	// not cut the section there.
+	input := "[a]\nB: x\n[\\a]\n[b]\nB: ``\n" + strings.Repeat("x", 4080) + "\n[\\b]\n``\n[\\b]\n"
+	readers := []func(io.Reader) io.Reader{
+		func(r io.Reader) io.Reader { return r },
+		iotest.OneByteReader,
+		iotest.HalfReader,
+	}
+
+	for i, wrap := range readers {
+		dec := NewDecoder(wrap(strings.NewReader(input)))
+		var c struct {
+			B string `gurlf:"B"`
+		}
+		if err := dec.Decode(&c); err != nil || c.B != "x" {
+			t.Fatalf("[%d]: expected x, got %q, %v", i, c.B, err)
+		}
+		if err := dec.Decode(&c); err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		if exp := strings.Repeat("x", 4080) + "\n[\\b]"; c.B != exp {
+			t.Errorf("[%d]: expected the whole block, got %d bytes", i, len(c.B))
+		}
+		if err := dec.Decode(&c); err != io.EOF {
+			t.Errorf("[%d]: expected io.EOF, got %v", i, err)
+		}
+	}
+}
+
+func TestDecoderStrict(t *testing.T) {
+	input := "[a]\nID: 1\n[\\a]\n\n[b]\nID: 2\nIDD: 3\n[\\b]\n"
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	dec.DisallowUnknownKeys()
+
+	var c struct {
+		ID int `gurlf:"ID"`
+	}
+	if err := dec.Decode(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := dec.Decode(&c)
+	var ke *KeyError
+	if !errors.As(err, &ke) {
+		t.Fatalf("expected *KeyError, got %v", err)
+	}
+	if ke.Key != "IDD" || ke.Section != "b" || ke.Line != 7 {
+		t.Errorf("expected IDD in section b at line 7, got %v", ke)
+	}
+}
+
+func TestDecoderExpand(t *testing.T) {
+	input := "[a]\nURL: ${HOST}/a\n[\\a]\n\n[b]\nURL: ${a.URL}\n[\\b]\n\n[c]\nURL: ${a.URL}\n[\\c]\n"
+	dec := NewDecoder(strings.NewReader(input))
+	dec.Expand(MapResolver{"HOST": "localhost", "a.URL": "from resolver"})
+
+	var c struct {
+		URL string `gurlf:"URL"`
+	}
+	if err := dec.Decode(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.URL != "localhost/a" {
+		t.Errorf("expected %q, got %q", "localhost/a", c.URL)
+	}
+
+	// Other sections are not kept, so the resolver answers for them.
+	if err := dec.Decode(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.URL != "from resolver" {
+		t.Errorf("expected %q, got %q", "from resolver", c.URL)
+	}
+
+	dec.Expand(MapResolver{"HOST": "localhost"})
+	err := dec.Decode(&c)
+	var ke *KeyError
+	if !errors.Is(err, ErrUnresolvedRef) || !errors.As(err, &ke) || ke.Line != 10 {
+		t.Errorf("expected unresolved reference at line 10, got %v", err)
+	}
+}
+
+// chunkReader returns at most n bytes per Read, like a pipe.
+type chunkReader struct {
+	r io.Reader
+	n int
+}
+
+func (c chunkReader) Read(p []byte) (int, error) {
+	return c.r.Read(p[:min(len(p), c.n)])
+}
+
+func BenchmarkDecoderLargeSection(b *testing.B) {
+	input := "[big]\nBODY: `\n" + strings.Repeat("0123456789abcdef\n", 1<<18) + "`\n[\\big]\n"
+	var c struct {
+		Body string `gurlf:"BODY"`
+	}
+
+	for b.Loop() {
+		dec := NewDecoder(chunkReader{strings.NewReader(input), 4096})
+		if err := dec.Decode(&c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	input := strings.Repeat("[config]\nID: 15\nProject: WhereBear\n[\\config]\n", 64)
+	type cfg struct {
+		ID      int    `gurlf:"ID"`
+		Project string `gurlf:"Project"`
+	}
+
+	for b.Loop() {
+		dec := NewDecoder(strings.NewReader(input))
+		for dec.More() {
+			var c cfg
+			if err := dec.Decode(&c); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -3,6 +3,9 @@ module github.com/Votline/Gurlf
 go 1.25.5
 
 require (
-	go.uber.org/multierr v1.10.0 // indirect
-	go.uber.org/zap v1.27.1 // indirect
+	github.com/BurntSushi/toml v1.5.0
+	go.uber.org/zap v1.27.1
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require go.uber.org/multierr v1.10.0 // indirect
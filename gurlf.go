@@ -8,26 +8,84 @@ import (
 	"github.com/Votline/Gurlf/pkg/scanner"
 )
 
+type (
+	Unmarshaler      = core.Unmarshaler
+	Marshaler        = core.Marshaler
+	UnmarshalOptions = core.UnmarshalOptions
+	KeyError         = core.KeyError
+
+	SectionUnmarshaler = core.SectionUnmarshaler
+	SectionAppender    = core.SectionAppender
+
+	Resolver      = core.Resolver
+	ResolverFunc  = core.ResolverFunc
+	EnvResolver   = core.EnvResolver
+	MapResolver   = core.MapResolver
+	MultiResolver = core.MultiResolver
+)
+
+var (
+	ErrUnknownKey   = core.ErrUnknownKey
+	ErrDuplicateKey = core.ErrDuplicateKey
+	ErrMissingKey   = core.ErrMissingKey
+	ErrInvalidKey   = core.ErrInvalidKey
+	ErrInvalidName  = core.ErrInvalidName
+
+	ErrUnresolvedRef  = core.ErrUnresolvedRef
+	ErrRefCycle       = core.ErrRefCycle
+	ErrBadRef         = core.ErrBadRef
+	ErrExpandTooLarge = core.ErrExpandTooLarge
+)
+
+// Scan returns every section of d. The result owns its entries and is safe
+// to keep after Scan returns; names and values still point into d.
 func Scan(d []byte) ([]scanner.Data, error) {
 	s := scanner.ScannerPool.Get().(*scanner.Scanner)
-	defer scanner.ScannerPool.Put(s)
-	return s.Scan(d)
-}
+	defer s.Release()
 
-func ScanFile(p string) ([]scanner.Data, error) {
-	d, err := os.ReadFile(p)
+	res, err := s.Scan(d)
 	if err != nil {
 		return nil, err
 	}
-	s := scanner.ScannerPool.Get().(*scanner.Scanner)
-	defer scanner.ScannerPool.Put(s)
-	return s.Scan(d)
+	return scanner.Detach(res), nil
+}
+
+// ScanFile returns every section of the file p, with includes replaced
+// by the sections of the files they name:
+//
+//	@include "common.gurlf"
+//	[include]
+//	FILES: `
+//	shared/*.gurlf
+//	`
+//	[\include]
+//
+// Paths are relative to the including file and may be glob patterns.
+// Each file is loaded once; an include that leads back to a file being
+// loaded fails with ErrIncludeCycle. Line numbers and offsets of the
+// sections refer to the file they come from.
+func ScanFile(p string) ([]scanner.Data, error) {
+	in := includer{seen: make(map[string]bool)}
+	return in.load(p)
 }
 
 func Unmarshal(d scanner.Data, v any) error {
 	return core.Unmarshal(d, v)
 }
 
+// UnmarshalAll scans d and stores all of its sections in v.
+// See core.UnmarshalAll for the accepted targets.
+func UnmarshalAll(d []byte, v any) error {
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+
+	ds, err := s.Scan(d)
+	if err != nil {
+		return err
+	}
+	return core.UnmarshalAll(ds, v)
+}
+
 func Marshal(v any) ([]byte, error) {
 	return core.Marshal(v)
 }
@@ -0,0 +1,80 @@
+package gurlf
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestScanConcurrent(t *testing.T) {
+	const workers, rounds = 32, 200
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			input := fmt.Appendf(nil, "[w%d]\nID: %d\nUser: u%d\n[\\w%d]\n", w, w, w, w)
+			var kept [][]byte
+			for r := range rounds {
+				data, err := Scan(input)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if r%10 == 0 {
+					kept = append(kept, data[0].RawData[data[0].Entries[0].ValStart:data[0].Entries[0].ValEnd])
+				}
+
+				ent := data[0].Entries[1]
+				if got := string(data[0].RawData[ent.ValStart:ent.ValEnd]); got != "u"+strconv.Itoa(w) {
+					errs <- fmt.Errorf("[%d]: expected %q, got %q", w, "u"+strconv.Itoa(w), got)
+					return
+				}
+			}
+
+			for _, k := range kept {
+				if string(k) != strconv.Itoa(w) {
+					errs <- fmt.Errorf("[%d]: kept value changed to %q", w, k)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestScanKeepsEntries(t *testing.T) {
+	first, err := Scan([]byte("[a]\nID: 1\nUser: admin\n[\\a]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Scan([]byte("[b]\nProject: Gurlf\n[\\b]\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key string
+		val string
+	}{
+		{"ID", "1"},
+		{"User", "admin"},
+	}
+	d := first[0]
+	for i, tt := range tests {
+		ent := d.Entries[i]
+		key := string(d.RawData[ent.KeyStart:ent.KeyEnd])
+		val := string(d.RawData[ent.ValStart:ent.ValEnd])
+		if key != tt.key || val != tt.val {
+			t.Errorf("[%d]: expected %s=%q, got %s=%q", i, tt.key, tt.val, key, val)
+		}
+	}
+}
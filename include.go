@@ -0,0 +1,225 @@
+package gurlf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+var ErrIncludeCycle = errors.New("include cycle")
+
+// IncludeError reports a file pulled in by an include that could not be
+// loaded. An error inside a nested include is itself an IncludeError, so
+// the message reads as the include chain.
+type IncludeError struct {
+	// File and Line locate the include.
+	File string
+	Line int
+	// Path is the included path or pattern as written.
+	Path string
+	Err  error
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("%s:%d: include %q: %v", e.File, e.Line, e.Path, e.Err)
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// include is one path or pattern to load, at offset off of its file.
+type include struct {
+	off, line int
+	path      string
+}
+
+type includer struct {
+	seen  map[string]bool
+	stack []string
+	names []string
+}
+
+// load scans p and splices the sections of its includes in where they
+// are written. A file already loaded is skipped, so shared files may be
+// included from several places.
+func (in *includer) load(p string) ([]scanner.Data, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	if i := slices.Index(in.stack, abs); i != -1 {
+		chain := append(slices.Clone(in.names[i:]), p)
+		return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(chain, " -> "))
+	}
+	if in.seen[abs] {
+		return nil, nil
+	}
+	in.seen[abs] = true
+
+	d, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	ds, incs, err := scanIncludes(d)
+	if err != nil {
+		if len(in.stack) > 0 {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		return nil, err
+	}
+	if len(incs) == 0 {
+		return ds, nil
+	}
+
+	in.stack, in.names = append(in.stack, abs), append(in.names, p)
+	defer func() {
+		in.stack, in.names = in.stack[:len(in.stack)-1], in.names[:len(in.names)-1]
+	}()
+
+	res := make([]scanner.Data, 0, len(ds))
+	for _, inc := range incs {
+		for len(ds) > 0 && ds[0].Offset < inc.off {
+			res, ds = append(res, ds[0]), ds[1:]
+		}
+
+		files, err := includePaths(filepath.Dir(p), inc.path)
+		if err != nil {
+			return nil, &IncludeError{File: p, Line: inc.line, Path: inc.path, Err: err}
+		}
+		if isGlob(inc.path) {
+			// A pattern may match the files including it, which is not
+			// a cycle; a literal path to them still is.
+			files = slices.DeleteFunc(files, in.loading)
+		}
+		for _, f := range files {
+			sub, err := in.load(f)
+			if err != nil {
+				return nil, &IncludeError{File: p, Line: inc.line, Path: inc.path, Err: err}
+			}
+			res = append(res, sub...)
+		}
+	}
+	return append(res, ds...), nil
+}
+
+// loading reports whether p is one of the files being loaded.
+func (in *includer) loading(p string) bool {
+	abs, err := filepath.Abs(p)
+	return err == nil && slices.Contains(in.stack, abs)
+}
+
+// includePaths returns the files path names, relative to dir unless it
+// is absolute. Patterns are expanded with filepath.Glob and may match
+// nothing.
+func includePaths(dir, path string) ([]string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if !isGlob(path) {
+		return []string{path}, nil
+	}
+	return filepath.Glob(path)
+}
+
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// scanIncludes scans d and collects its includes: "@include PATH" lines
+// outside of sections and the lines of every value in a top-level
+// [include] section, which is dropped from the result.
+func scanIncludes(d []byte) ([]scanner.Data, []include, error) {
+	var incs []include
+	buf := d
+	for off, line := 0, 1; off < len(d); line++ {
+		end := lineEnd(d, off)
+		if path, ok := directive(d[off:end]); ok {
+			if len(incs) == 0 {
+				buf = bytes.Clone(d)
+			}
+			incs = append(incs, include{off: off, line: line, path: path})
+			blankLine(buf[off:end])
+		}
+		off = end
+	}
+
+	ds, err := Scan(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Directive lines inside sections are values, not includes.
+	restored := false
+	incs = slices.DeleteFunc(incs, func(inc include) bool {
+		for _, dt := range ds {
+			if inc.off >= dt.Offset && inc.off < dt.Offset+len(dt.RawData) {
+				end := lineEnd(d, inc.off)
+				copy(buf[inc.off:end], d[inc.off:end])
+				restored = true
+				return true
+			}
+		}
+		return false
+	})
+	if restored {
+		if ds, err = Scan(buf); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ds = slices.DeleteFunc(ds, func(dt scanner.Data) bool {
+		if string(dt.Name) != "include" {
+			return false
+		}
+		for _, ent := range dt.Entries {
+			line := dt.LineOf(ent.ValStart)
+			for l := range strings.Lines(string(dt.RawData[ent.ValStart:ent.ValEnd])) {
+				if p := strings.TrimSpace(l); p != "" && p[0] != '#' {
+					incs = append(incs, include{off: dt.Offset, line: line, path: p})
+				}
+				line++
+			}
+		}
+		return true
+	})
+	slices.SortStableFunc(incs, func(a, b include) int { return a.off - b.off })
+
+	return ds, incs, nil
+}
+
+// directive reports the path of an "@include" line.
+func directive(line []byte) (string, bool) {
+	rest, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("@include"))
+	if !ok || len(rest) == 0 || (rest[0] != ' ' && rest[0] != '\t') {
+		return "", false
+	}
+	path := string(bytes.TrimSpace(rest))
+	if p, err := strconv.Unquote(path); err == nil {
+		path = p
+	}
+	return path, path != ""
+}
+
+// lineEnd returns the offset just past the line starting at off.
+func lineEnd(d []byte, off int) int {
+	if i := bytes.IndexByte(d[off:], '\n'); i != -1 {
+		return off + i + 1
+	}
+	return len(d)
+}
+
+func blankLine(line []byte) {
+	for i, c := range line {
+		if c != '\n' {
+			line[i] = ' '
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package gurlf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestScanFileInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.gurlf": "@include \"common.gurlf\"\n[login]\nBODY: `\n@include \"kept.gurlf\"\n`\n[\\login]\n" +
+			"[include]\nFILES: `\nteams/*.gurlf\n# teams/old.gurlf\n`\n[\\include]\n[logout]\nK: v\n[\\logout]\n",
+		"common.gurlf":  "@include base.gurlf\n[common]\nK: v\n[\\common]\n",
+		"base.gurlf":    "[base]\nK: v\n[\\base]\n",
+		"teams/a.gurlf": "@include ../common.gurlf\n[a]\nK: v\n[\\a]\n",
+		"teams/b.gurlf": "[b]\nK: v\n[\\b]\n",
+	})
+
+	ds, err := ScanFile(filepath.Join(dir, "main.gurlf"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, d := range ds {
+		names = append(names, string(d.Name))
+	}
+	if got, want := strings.Join(names, " "), "base common login a b logout"; got != want {
+		t.Errorf("expected sections %q, got %q", want, got)
+	}
+	if body := string(ds[2].RawData[ds[2].Entries[0].ValStart:ds[2].Entries[0].ValEnd]); body != "\n@include \"kept.gurlf\"\n" {
+		t.Errorf("expected the block as written, got %q", body)
+	}
+	if ds[1].Line != 2 {
+		t.Errorf("expected common at line 2 of its file, got %d", ds[1].Line)
+	}
+}
+
+func TestScanFileIncludeGlobSelf(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.gurlf":    "@include \"*.gurlf\"\n@include teams/*.gurlf\n[main]\nK: v\n[\\main]\n",
+		"common.gurlf":  "[common]\nK: v\n[\\common]\n",
+		"teams/a.gurlf": "@include *.gurlf\n@include ../*.gurlf\n[a]\nK: v\n[\\a]\n",
+		"teams/b.gurlf": "[b]\nK: v\n[\\b]\n",
+	})
+
+	ds, err := ScanFile(filepath.Join(dir, "main.gurlf"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, d := range ds {
+		names = append(names, string(d.Name))
+	}
+	if got, want := strings.Join(names, " "), "common b a main"; got != want {
+		t.Errorf("expected sections %q, got %q", want, got)
+	}
+
+	dir = writeFiles(t, map[string]string{"self.gurlf": "@include self.gurlf\n[a]\nK: v\n[\\a]\n"})
+	if _, err := ScanFile(filepath.Join(dir, "self.gurlf")); !errors.Is(err, ErrIncludeCycle) {
+		t.Errorf("expected ErrIncludeCycle for a literal self include, got %v", err)
+	}
+}
+
+func TestScanFileIncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.gurlf":   "[a]\nK: v\n[\\a]\n@include \"loop.gurlf\"\n",
+		"loop.gurlf":   "[include]\nFILE: main.gurlf\n[\\include]\n",
+		"broken.gurlf": "[a]\nK: v\n\n@include missing.gurlf\n",
+		"bad.gurlf":    "@include broken.gurlf\n",
+	})
+
+	_, err := ScanFile(filepath.Join(dir, "main.gurlf"))
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("expected ErrIncludeCycle, got %v", err)
+	}
+	var ie *IncludeError
+	if !errors.As(err, &ie) || ie.Line != 4 || ie.Path != "loop.gurlf" {
+		t.Errorf("expected include of loop.gurlf at line 4, got %v", err)
+	}
+	for _, s := range []string{"main.gurlf:4:", "loop.gurlf:2:", "main.gurlf -> ", "loop.gurlf -> ", "main.gurlf"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected %q in %q", s, err)
+		}
+	}
+
+	_, err = ScanFile(filepath.Join(dir, "bad.gurlf"))
+	if !errors.As(err, &ie) || !strings.Contains(err.Error(), "broken.gurlf: ") {
+		t.Errorf("expected a syntax error in broken.gurlf, got %v", err)
+	}
+}
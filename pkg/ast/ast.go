@@ -0,0 +1,509 @@
+// Package ast holds a lossless syntax tree of a gurlf file. Every byte of
+// the input, including whitespace, comments and backtick delimiters, lives
+// in some node, so a parsed file prints back exactly as it was read and
+// edits only rewrite the nodes they touch.
+package ast
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Votline/Gurlf/pkg/core"
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+// Node is a piece of a file: *Trivia, *Comment, *Entry or *Section.
+type Node interface {
+	appendTo(dst []byte) []byte
+}
+
+// Trivia is text the scanner skips: whitespace and stray characters
+// between entries and sections.
+type Trivia struct {
+	Text []byte
+}
+
+// Comment is a '#' line, without its line break.
+type Comment struct {
+	Text []byte
+}
+
+// Entry is a single KEY: value pair.
+type Entry struct {
+	key   string
+	value []byte
+	raw   []byte
+	vpos  int
+}
+
+// Section is a [name]...[\name] block. Nodes is everything between the
+// header and the closing tag.
+type Section struct {
+	Name  string
+	Nodes []Node
+	open  []byte
+	close []byte
+}
+
+// File is a parsed gurlf file. Nodes is the top level: sections and the
+// trivia and comments around them.
+type File struct {
+	Nodes []Node
+}
+
+// Parse builds the tree of src. The nodes reference src, which must not
+// be modified while the tree is in use.
+func Parse(src []byte) (*File, error) {
+	const op = "ast.Parse"
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+
+	ds, err := s.Scan(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	f := &File{}
+	pos := 0
+	for i := range ds {
+		sec, start, end := build(src, &ds[i])
+		f.Nodes = gap(f.Nodes, src, pos, start)
+		f.Nodes = append(f.Nodes, sec)
+		pos = end
+	}
+	f.Nodes = gap(f.Nodes, src, pos, len(src))
+
+	return f, nil
+}
+
+// build turns d into a section and reports the bounds of its source,
+// from the opening '[' to the end of the closing tag.
+func build(src []byte, d *scanner.Data) (sec *Section, start, end int) {
+	start = d.Offset - len(d.Name) - 2
+	body := d.Offset + len(d.RawData)
+	end = body + len(d.Name) + 3
+
+	sec = &Section{
+		Name:  string(d.Name),
+		open:  src[start:d.Offset],
+		close: src[body:end],
+	}
+
+	pos, kid := d.Offset, 0
+	for _, en := range d.Entries {
+		kS := d.Offset + en.KeyStart
+		for ; kid < len(d.Children) && d.Children[kid].Offset < kS; kid++ {
+			pos = sec.child(src, pos, &d.Children[kid])
+		}
+
+		sec.Nodes = gap(sec.Nodes, src, pos, kS)
+		e := entry(src, d, en)
+		sec.Nodes = append(sec.Nodes, e)
+		pos = kS + len(e.raw)
+	}
+	for ; kid < len(d.Children); kid++ {
+		pos = sec.child(src, pos, &d.Children[kid])
+	}
+	sec.Nodes = gap(sec.Nodes, src, pos, body)
+
+	return sec, start, end
+}
+
+func (s *Section) child(src []byte, pos int, d *scanner.Data) int {
+	kid, start, end := build(src, d)
+	s.Nodes = gap(s.Nodes, src, pos, start)
+	s.Nodes = append(s.Nodes, kid)
+	return end
+}
+
+// entry cuts en out of src: from the key to the closing delimiter of the
+// value, leaving the line break to the trivia that follows.
+func entry(src []byte, d *scanner.Data, en scanner.Entry) *Entry {
+	kS := d.Offset + en.KeyStart
+	vS, vE := d.Offset+en.ValStart, d.Offset+en.ValEnd
+
+	vpos := d.Offset + en.KeyEnd + bytes.IndexByte(src[d.Offset+en.KeyEnd:], ':') + 1
+	for vpos < vS && src[vpos] == ' ' {
+		vpos++
+	}
+
+	end := vE
+	if k := ticks(src[vpos:vS]); k > 1 && vS > vpos+k {
+		end = vE + 1 + k
+	} else if k > 0 {
+		end = vE + 1
+	}
+
+	return &Entry{
+		key:   string(src[kS : d.Offset+en.KeyEnd]),
+		value: src[vS:vE],
+		raw:   src[kS:end],
+		vpos:  vpos - kS,
+	}
+}
+
+func ticks(b []byte) int {
+	k := 0
+	for k < len(b) && b[k] == '`' {
+		k++
+	}
+	return k
+}
+
+// gap splits src[from:to] into trivia and the comment lines inside it.
+func gap(nodes []Node, src []byte, from, to int) []Node {
+	start, lineStart := from, from == 0 || src[from-1] == '\n'
+	for i := from; i < to; i++ {
+		switch c := src[i]; {
+		case c == '\n':
+			lineStart = true
+		case c == '#' && lineStart:
+			if start < i {
+				nodes = append(nodes, &Trivia{Text: src[start:i]})
+			}
+			end := i + bytes.IndexByte(src[i:to], '\n')
+			if end < i {
+				end = to
+			}
+			if end > i && src[end-1] == '\r' {
+				end--
+			}
+			nodes = append(nodes, &Comment{Text: src[i:end]})
+			start, i = end, end-1
+		case c != ' ' && c != '\t':
+			lineStart = false
+		}
+	}
+	if start < to {
+		nodes = append(nodes, &Trivia{Text: src[start:to]})
+	}
+	return nodes
+}
+
+// Bytes prints f.
+func (f *File) Bytes() []byte {
+	var dst []byte
+	for _, n := range f.Nodes {
+		dst = n.appendTo(dst)
+	}
+	return dst
+}
+
+// Sections returns the top-level sections of f.
+func (f *File) Sections() []*Section { return sections(f.Nodes) }
+
+// Section returns the first top-level section called name, or nil.
+func (f *File) Section(name string) *Section { return section(f.Nodes, name) }
+
+// AddSection appends an empty section called name to f, separated from
+// the previous one by a blank line. It fails with core.ErrInvalidName if
+// name cannot be written as a section name.
+func (f *File) AddSection(name string) (*Section, error) {
+	const op = "ast.File.AddSection"
+
+	if !core.ValidName(name) {
+		return nil, fmt.Errorf("%s: %w %q", op, core.ErrInvalidName, name)
+	}
+	sec := newSection(name, "")
+
+	var sep []byte
+	if b := f.Bytes(); len(b) > 0 {
+		if b[len(b)-1] != '\n' {
+			sep = append(sep, '\n')
+		}
+		if !bytes.HasSuffix(b, []byte("\n\n")) {
+			sep = append(sep, '\n')
+		}
+	}
+	if len(sep) > 0 {
+		f.Nodes = append(f.Nodes, &Trivia{Text: sep})
+	}
+	f.Nodes = append(f.Nodes, sec, &Trivia{Text: []byte("\n")})
+
+	return sec, nil
+}
+
+// RemoveSection deletes the first top-level section called name along
+// with the blank lines that separated it, and reports whether it existed.
+func (f *File) RemoveSection(name string) bool {
+	i := index(f.Nodes, name)
+	if i == -1 {
+		return false
+	}
+
+	// Keep the separator on the side that has more sections.
+	drop := i + 1
+	if i+2 >= len(f.Nodes) && i > 0 {
+		drop = i - 1
+	}
+	if t, ok := at(f.Nodes, drop).(*Trivia); ok && len(bytes.TrimSpace(t.Text)) == 0 {
+		f.Nodes = remove(f.Nodes, max(drop, i))
+		f.Nodes = remove(f.Nodes, min(drop, i))
+		return true
+	}
+
+	f.Nodes = remove(f.Nodes, i)
+	return true
+}
+
+func (s *Section) appendTo(dst []byte) []byte {
+	dst = append(dst, s.open...)
+	for _, n := range s.Nodes {
+		dst = n.appendTo(dst)
+	}
+	return append(dst, s.close...)
+}
+
+// Entries returns the entries of s in file order.
+func (s *Section) Entries() []*Entry {
+	var res []*Entry
+	for _, n := range s.Nodes {
+		if e, ok := n.(*Entry); ok {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Entry returns the last entry called key, the one Unmarshal would use,
+// or nil.
+func (s *Section) Entry(key string) *Entry {
+	if i := s.last(key); i != -1 {
+		return s.Nodes[i].(*Entry)
+	}
+	return nil
+}
+
+// Set changes the value of the last entry called key or, when there is
+// none, adds it on a new line after the other entries. It fails with
+// core.ErrInvalidKey if key would not read back as the same key.
+func (s *Section) Set(key, value string) error {
+	if i := s.last(key); i != -1 {
+		s.Nodes[i].(*Entry).SetValue(value)
+		s.Nodes = lineBreak(s.Nodes, i+1)
+		return nil
+	}
+
+	e, err := NewEntry(key, value)
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for j, n := range s.Nodes {
+		if _, ok := n.(*Entry); ok {
+			i = j + 1
+		}
+	}
+	s.Nodes = insertLine(s.Nodes, i, indent(s.Nodes, i-1), e)
+	return nil
+}
+
+// Delete removes every entry called key and the lines they were on.
+// It reports whether any was found.
+func (s *Section) Delete(key string) bool {
+	found := false
+	for i := len(s.Nodes) - 1; i >= 0; i-- {
+		if e, ok := s.Nodes[i].(*Entry); ok && e.key == key {
+			s.Nodes = removeLine(s.Nodes, i)
+			found = true
+		}
+	}
+	return found
+}
+
+// Sections returns the sections nested directly in s.
+func (s *Section) Sections() []*Section { return sections(s.Nodes) }
+
+// Section returns the first section called name nested directly in s,
+// or nil.
+func (s *Section) Section(name string) *Section { return section(s.Nodes, name) }
+
+// AddSection appends an empty section called name at the end of s. It
+// fails with core.ErrInvalidName if name cannot be written as a section
+// name.
+func (s *Section) AddSection(name string) (*Section, error) {
+	const op = "ast.Section.AddSection"
+
+	if !core.ValidName(name) {
+		return nil, fmt.Errorf("%s: %w %q", op, core.ErrInvalidName, name)
+	}
+	i := 0
+	for j, n := range s.Nodes {
+		if _, ok := n.(*Trivia); !ok {
+			i = j + 1
+		}
+	}
+
+	ind := indent(s.Nodes, i-1)
+	sec := newSection(name, ind)
+	s.Nodes = insertLine(s.Nodes, i, ind, sec)
+	return sec, nil
+}
+
+// RemoveSection deletes the first section called name nested directly
+// in s and reports whether it existed.
+func (s *Section) RemoveSection(name string) bool {
+	i := index(s.Nodes, name)
+	if i == -1 {
+		return false
+	}
+	s.Nodes = removeLine(s.Nodes, i)
+	return true
+}
+
+func (s *Section) last(key string) int {
+	for i := len(s.Nodes) - 1; i >= 0; i-- {
+		if e, ok := s.Nodes[i].(*Entry); ok && e.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func newSection(name, indent string) *Section {
+	return &Section{
+		Name:  name,
+		Nodes: []Node{&Trivia{Text: []byte("\n" + indent)}},
+		open:  []byte("[" + name + "]"),
+		close: []byte("[\\" + name + "]"),
+	}
+}
+
+// NewEntry returns a "KEY: value" entry, with value encoded the way
+// Marshal would. It fails with core.ErrInvalidKey if key would not read
+// back as the same key.
+func NewEntry(key, value string) (*Entry, error) {
+	const op = "ast.NewEntry"
+
+	if !core.ValidKey(key) {
+		return nil, fmt.Errorf("%s: %w %q", op, core.ErrInvalidKey, key)
+	}
+	e := &Entry{key: key, raw: []byte(key + ": "), vpos: len(key) + 2}
+	e.SetValue(value)
+	return e, nil
+}
+
+func (e *Entry) appendTo(dst []byte) []byte { return append(dst, e.raw...) }
+
+// Key returns the name of e.
+func (e *Entry) Key() string { return e.key }
+
+// Value returns the value of e without its backtick delimiters.
+func (e *Entry) Value() []byte { return e.value }
+
+// SetValue replaces the value of e, keeping the key and the spacing
+// around the colon.
+func (e *Entry) SetValue(v string) {
+	head := e.raw[:e.vpos]
+	if v == "" {
+		head = bytes.TrimRight(head, " ")
+	}
+
+	raw := make([]byte, 0, len(head)+len(v)+2)
+	raw = append(raw, head...)
+	raw = core.AppendText(raw, v)
+
+	e.raw, e.vpos, e.value = raw, len(head), []byte(v)
+}
+
+func (t *Trivia) appendTo(dst []byte) []byte  { return append(dst, t.Text...) }
+func (c *Comment) appendTo(dst []byte) []byte { return append(dst, c.Text...) }
+
+func sections(nodes []Node) []*Section {
+	var res []*Section
+	for _, n := range nodes {
+		if s, ok := n.(*Section); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func section(nodes []Node, name string) *Section {
+	if i := index(nodes, name); i != -1 {
+		return nodes[i].(*Section)
+	}
+	return nil
+}
+
+func index(nodes []Node, name string) int {
+	for i, n := range nodes {
+		if s, ok := n.(*Section); ok && s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func at(nodes []Node, i int) Node {
+	if i < 0 || i >= len(nodes) {
+		return nil
+	}
+	return nodes[i]
+}
+
+func remove(nodes []Node, i int) []Node {
+	return append(nodes[:i], nodes[i+1:]...)
+}
+
+// indent returns the spaces that open the line of nodes[i].
+func indent(nodes []Node, i int) string {
+	t, ok := at(nodes, i-1).(*Trivia)
+	if !ok {
+		return ""
+	}
+	nl := bytes.LastIndexByte(t.Text, '\n')
+	if nl == -1 {
+		return ""
+	}
+	ind := t.Text[nl+1:]
+	if len(bytes.Trim(ind, " \t")) != 0 {
+		return ""
+	}
+	return string(ind)
+}
+
+// insertLine puts n on a new line before nodes[i].
+func insertLine(nodes []Node, i int, indent string, n Node) []Node {
+	nodes = append(nodes[:i], append([]Node{&Trivia{Text: []byte("\n" + indent)}, n}, nodes[i:]...)...)
+	return lineBreak(nodes, i+2)
+}
+
+// lineBreak makes sure nodes[i] starts a new line, so that a plain value
+// before it stays terminated.
+func lineBreak(nodes []Node, i int) []Node {
+	if t, ok := at(nodes, i).(*Trivia); ok {
+		if bytes.HasPrefix(t.Text, []byte("\n")) || bytes.HasPrefix(t.Text, []byte("\r\n")) {
+			return nodes
+		}
+	}
+	return append(nodes[:i], append([]Node{&Trivia{Text: []byte("\n")}}, nodes[i:]...)...)
+}
+
+// removeLine deletes nodes[i] together with the line break and the
+// indentation in front of it.
+func removeLine(nodes []Node, i int) []Node {
+	nodes = remove(nodes, i)
+
+	if t, ok := at(nodes, i-1).(*Trivia); ok {
+		if nl := bytes.LastIndexByte(t.Text, '\n'); nl != -1 &&
+			len(bytes.Trim(t.Text[nl+1:], " \t")) == 0 {
+			text := bytes.TrimSuffix(t.Text[:nl], []byte("\r"))
+			if len(text) == 0 {
+				return remove(nodes, i-1)
+			}
+			t.Text = text
+			return nodes
+		}
+	}
+
+	if t, ok := at(nodes, i).(*Trivia); ok {
+		if nl := bytes.IndexByte(t.Text, '\n'); nl != -1 &&
+			len(bytes.TrimSpace(t.Text[:nl])) == 0 {
+			t.Text = t.Text[nl+1:]
+		}
+	}
+	return nodes
+}
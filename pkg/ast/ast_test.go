@@ -0,0 +1,203 @@
+package ast
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Votline/Gurlf/pkg/core"
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+const src = "# requests\r\n" +
+	"[login]\n" +
+	"  # credentials\n" +
+	"  ID :  1\n" +
+	"  BODY: `{\"user\": \"dev\"}`\n" +
+	"  NOTE: ```\n" +
+	"line one\n" +
+	"``\n" +
+	"```\n" +
+	"  [auth]\n" +
+	"    USER:dev\n" +
+	"  [\\auth]\n" +
+	"  TAIL: `\n" +
+	"multi\n" +
+	"`\n" +
+	"[\\login]\n" +
+	"\n" +
+	"\n" +
+	"[logout]\n" +
+	"ID: 2\n" +
+	"[\\logout]\n"
+
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		src,
+		"",
+		"\n\n# only a comment",
+		"[a][\\a]",
+		"junk [a]\r\nK: v\r\n[\\a]\r\n\r\n",
+	}
+
+	for i, in := range inputs {
+		f, err := Parse([]byte(in))
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		if got := string(f.Bytes()); got != in {
+			t.Errorf("[%d]: expected\n%q\ngot\n%q", i, in, got)
+		}
+	}
+}
+
+func TestTree(t *testing.T) {
+	f, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c, ok := f.Nodes[0].(*Comment); !ok || string(c.Text) != "# requests" {
+		t.Errorf("expected leading comment, got %#v", f.Nodes[0])
+	}
+
+	login := f.Section("login")
+	if login == nil || len(f.Sections()) != 2 {
+		t.Fatalf("unexpected sections: %+v", f.Sections())
+	}
+
+	wants := map[string]string{
+		"ID":   "1",
+		"BODY": `{"user": "dev"}`,
+		"NOTE": "line one\n``",
+		"TAIL": "\nmulti\n",
+	}
+	var keys []string
+	for _, e := range login.Entries() {
+		keys = append(keys, e.Key())
+		if string(e.Value()) != wants[e.Key()] {
+			t.Errorf("%s: expected %q, got %q", e.Key(), wants[e.Key()], e.Value())
+		}
+	}
+	if strings.Join(keys, ",") != "ID,BODY,NOTE,TAIL" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	if e := login.Section("auth").Entry("USER"); e == nil || string(e.Value()) != "dev" {
+		t.Errorf("unexpected nested entry: %+v", e)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(f *File)
+		want string
+	}{
+		{
+			name: "set keeps spacing",
+			edit: func(f *File) { f.Section("login").Set("ID", "42") },
+			want: strings.Replace(src, "ID :  1", "ID :  42", 1),
+		},
+		{
+			name: "set multiline",
+			edit: func(f *File) { f.Section("logout").Set("ID", "a\nb") },
+			want: strings.Replace(src, "ID: 2\n", "ID: ``\na\nb\n``\n", 1),
+		},
+		{
+			name: "insert after last entry",
+			edit: func(f *File) { f.Section("login").Set("NEW", "x") },
+			want: strings.Replace(src, "multi\n`\n", "multi\n`\n  NEW: x\n", 1),
+		},
+		{
+			name: "insert into empty section",
+			edit: func(f *File) { f.Section("logout").Delete("ID"); f.Section("logout").Set("K", "") },
+			want: strings.Replace(src, "ID: 2\n", "K:\n", 1),
+		},
+		{
+			name: "delete",
+			edit: func(f *File) { f.Section("login").Delete("BODY") },
+			want: strings.Replace(src, "  BODY: `{\"user\": \"dev\"}`\n", "", 1),
+		},
+		{
+			name: "delete nested section",
+			edit: func(f *File) { f.Section("login").RemoveSection("auth") },
+			want: strings.Replace(src, "  [auth]\n    USER:dev\n  [\\auth]\n", "", 1),
+		},
+		{
+			name: "add nested section",
+			edit: func(f *File) { must(f.Section("logout").AddSection("auth")).Set("USER", "root") },
+			want: strings.Replace(src, "ID: 2\n", "ID: 2\n[auth]\nUSER: root\n[\\auth]\n", 1),
+		},
+		{
+			name: "remove last section",
+			edit: func(f *File) { f.RemoveSection("logout") },
+			want: src[:strings.Index(src, "\n\n")+1],
+		},
+		{
+			name: "remove first section",
+			edit: func(f *File) { f.RemoveSection("login") },
+			want: "# requests\r\n[logout]\nID: 2\n[\\logout]\n",
+		},
+		{
+			name: "add section",
+			edit: func(f *File) { must(f.AddSection("new")).Set("ID", "3") },
+			want: src + "\n[new]\nID: 3\n[\\new]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		f, err := Parse([]byte(src))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		tt.edit(f)
+
+		got := f.Bytes()
+		if string(got) != tt.want {
+			t.Errorf("%s: expected\n%q\ngot\n%q", tt.name, tt.want, got)
+		}
+
+		s := scanner.ScannerPool.Get().(*scanner.Scanner)
+		if _, err := s.Scan(got); err != nil {
+			t.Errorf("%s: output does not scan: %v", tt.name, err)
+		}
+		s.Release()
+	}
+}
+
+func must(s *Section, err error) *Section {
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestEditInvalid(t *testing.T) {
+	f, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sec := f.Section("login")
+
+	for _, key := range []string{"", "A:B", " A", "A\nB", "#A", "[x"} {
+		if err := sec.Set(key, "v"); !errors.Is(err, core.ErrInvalidKey) {
+			t.Errorf("Set(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+		if _, err := NewEntry(key, "v"); !errors.Is(err, core.ErrInvalidKey) {
+			t.Errorf("NewEntry(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+	for _, name := range []string{"", "a]b", "a\nb", "\\a"} {
+		if _, err := f.AddSection(name); !errors.Is(err, core.ErrInvalidName) {
+			t.Errorf("File.AddSection(%q): expected ErrInvalidName, got %v", name, err)
+		}
+		if _, err := sec.AddSection(name); !errors.Is(err, core.ErrInvalidName) {
+			t.Errorf("Section.AddSection(%q): expected ErrInvalidName, got %v", name, err)
+		}
+	}
+
+	if got := string(f.Bytes()); got != src {
+		t.Errorf("expected the file unchanged, got\n%q", got)
+	}
+}
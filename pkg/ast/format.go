@@ -0,0 +1,108 @@
+package ast
+
+import (
+	"bytes"
+
+	"github.com/Votline/Gurlf/pkg/core"
+)
+
+// Format returns src in canonical style: LF line breaks, no indentation,
+// "KEY: value" entries, multiline values encoded the way Marshal writes
+// them, one blank line between top-level sections and no runs of blank
+// lines. Comments are kept on their own lines.
+func Format(src []byte) ([]byte, error) {
+	f, err := Parse(lf(src))
+	if err != nil {
+		return nil, err
+	}
+
+	dst := formatNodes(nil, f.Nodes, true)
+	if len(dst) > 0 {
+		dst = append(dst, '\n')
+	}
+	return dst, nil
+}
+
+// lf turns every line break into "\n". The carriage returns before a
+// break are dropped all at once, so that formatting again finds none.
+func lf(src []byte) []byte {
+	if bytes.IndexByte(src, '\r') == -1 {
+		return src
+	}
+	dst := make([]byte, 0, len(src))
+	for {
+		i := bytes.IndexByte(src, '\n')
+		if i == -1 {
+			return append(dst, src...)
+		}
+		dst = append(dst, bytes.TrimRight(src[:i], "\r")...)
+		dst = append(dst, '\n')
+		src = src[i+1:]
+	}
+}
+
+// formatNodes writes nodes one per line. Blank lines between them are
+// kept but collapsed, and at the top level a section is always followed
+// by one.
+func formatNodes(dst []byte, nodes []Node, top bool) []byte {
+	var prev Node
+	blank := false
+	for _, n := range nodes {
+		t, ok := n.(*Trivia)
+		if ok {
+			blank = blank || bytes.Count(t.Text, []byte{'\n'}) > 1
+			if text := bytes.TrimSpace(t.Text); len(text) > 0 {
+				n = &Trivia{Text: text}
+			} else {
+				continue
+			}
+		}
+
+		if prev != nil {
+			dst = append(dst, '\n')
+			if _, sec := prev.(*Section); blank || (top && sec) {
+				dst = append(dst, '\n')
+			}
+		}
+		dst = formatNode(dst, n)
+		prev, blank = n, false
+	}
+	return dst
+}
+
+func formatNode(dst []byte, n Node) []byte {
+	switch n := n.(type) {
+	case *Entry:
+		dst = append(dst, n.key...)
+		dst = append(dst, ':')
+		if len(n.value) == 0 {
+			break
+		}
+		dst = append(dst, ' ')
+		// A one-line value keeps its backticks, which read as a block
+		// up to the last backtick of the line whatever the value holds,
+		// unless the line would be all backticks and open a fence.
+		if n.raw[n.vpos] == '`' && bytes.IndexByte(n.value, '\n') == -1 &&
+			len(bytes.TrimLeft(n.value, "`")) > 0 {
+			dst = append(dst, '`')
+			dst = append(dst, n.value...)
+			dst = append(dst, '`')
+		} else {
+			dst = core.AppendText(dst, string(n.value))
+		}
+	case *Section:
+		dst = append(dst, '[')
+		dst = append(dst, n.Name...)
+		dst = append(dst, "]\n"...)
+		if body := formatNodes(nil, n.Nodes, false); len(body) > 0 {
+			dst = append(dst, body...)
+			dst = append(dst, '\n')
+		}
+		dst = append(dst, "[\\"...)
+		dst = append(dst, n.Name...)
+		dst = append(dst, ']')
+	default:
+		dst = n.appendTo(dst)
+	}
+	return dst
+}
@@ -0,0 +1,134 @@
+package ast
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/Votline/Gurlf/pkg/core"
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+func TestFormat(t *testing.T) {
+	in := "\r\n# requests\r\n" +
+		"[login]\r\n" +
+		"\r\n" +
+		"  ID :  1\r\n" +
+		"\r\n\r\n" +
+		"  # body\r\n" +
+		"  BODY: `{\"user\": \"dev\"}`\r\n" +
+		"  EMPTY:\r\n" +
+		"  NOTE: `\r\n" +
+		"  indented\r\n" +
+		"`\r\n" +
+		"    [auth]\r\n" +
+		"    USER:dev\r\n" +
+		"    [\\auth]\r\n" +
+		"\r\n" +
+		"[\\login]\r\n" +
+		"[logout]\r\n" +
+		"[\\logout]"
+
+	want := "# requests\n" +
+		"[login]\n" +
+		"ID: 1\n" +
+		"\n" +
+		"# body\n" +
+		"BODY: `{\"user\": \"dev\"}`\n" +
+		"EMPTY:\n" +
+		"NOTE: `\n" +
+		"  indented\n" +
+		"`\n" +
+		"[auth]\n" +
+		"USER: dev\n" +
+		"[\\auth]\n" +
+		"[\\login]\n" +
+		"\n" +
+		"[logout]\n" +
+		"[\\logout]\n"
+
+	got, err := Format([]byte(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	again, err := Format(got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(again) != want {
+		t.Errorf("format is not idempotent:\n%s", again)
+	}
+
+	if _, err := Format([]byte("[a]\nK: v\n")); err == nil {
+		t.Error("expected error for unclosed section")
+	}
+}
+
+func TestFormatValues(t *testing.T) {
+	want := map[string]string{
+		"FENCE":  "``",
+		"TICK":   "`",
+		"TICKS":  "`x`",
+		"SPACE":  "  lead and trail  ",
+		"HASH":   "# not a comment",
+		"LINES":  "a\n`\n``\nb",
+		"INLINE": "a ` b",
+	}
+	src, err := core.Marshal(struct {
+		Name string            `gurlf:"config_name"`
+		Rest map[string]string `gurlf:",remain"`
+	}{"v", want})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Values written in backticks by hand as well.
+	src = append(src, "[w]\nA: ` spaced `\nB: `a`b`\n[\\w]\n"...)
+
+	got, err := Format(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := Format(got)
+	if err != nil {
+		t.Fatalf("unexpected error reformatting:\n%s\n%v", got, err)
+	}
+	if !bytes.Equal(again, got) {
+		t.Errorf("format is not idempotent:\n%s\n---\n%s", got, again)
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(got)
+	if err != nil {
+		t.Fatalf("formatted output does not scan: %v\n%s", err, got)
+	}
+	var secs map[string]map[string]string
+	if err := core.UnmarshalAll(ds, &secs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(secs["v"], want) {
+		t.Errorf("expected %q, got %q", want, secs["v"])
+	}
+	if w := secs["w"]; w["A"] != " spaced " || w["B"] != "a`b" {
+		t.Errorf("unexpected backtick values %q", w)
+	}
+}
+
+func TestFormatCarriageReturns(t *testing.T) {
+	src := []byte("[a]\r\nB: `\r\nx\r\r\ny\r\r\r\n`\r\nK: v\r\r\n[\\a]\r\n")
+
+	got, err := Format(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "[a]\nB: `\nx\ny\n`\nK: v\n[\\a]\n"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if again, err := Format(got); err != nil || !bytes.Equal(again, got) {
+		t.Errorf("format is not idempotent: %q, %v", again, err)
+	}
+}
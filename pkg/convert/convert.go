@@ -0,0 +1,151 @@
+// Package convert translates gurlf documents to and from JSON, YAML and
+// TOML. Sections become objects and keys become string fields; a name
+// used several times in a section becomes an array. Multiline values are
+// plain strings on the other side, so any document gurlf can represent
+// survives a round trip.
+package convert
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Votline/Gurlf/pkg/ast"
+	"github.com/Votline/Gurlf/pkg/core"
+)
+
+// Formats lists the names Convert accepts.
+var Formats = []string{"gurlf", "json", "yaml", "toml"}
+
+var ErrUnsupported = errors.New("cannot be represented")
+
+// object is a section body: keys and nested sections in file order.
+type object []field
+
+// field is a key when obj is nil and a nested section otherwise.
+type field struct {
+	name  string
+	value string
+	obj   object
+}
+
+type codec struct {
+	decode func(data []byte) (object, error)
+	encode func(doc object) ([]byte, error)
+}
+
+var codecs = map[string]codec{
+	"gurlf": {decodeGurlf, encodeGurlf},
+	"json":  {decodeJSON, encodeJSON},
+	"yaml":  {decodeYAML, encodeYAML},
+	"toml":  {decodeTOML, encodeTOML},
+}
+
+// Convert translates data from one of Formats to another. Values that
+// are not strings, such as JSON numbers, turn into their text.
+func Convert(data []byte, from, to string) ([]byte, error) {
+	const op = "convert.Convert"
+
+	dec, ok := codecs[from]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown format %q", op, from)
+	}
+	enc, ok := codecs[to]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown format %q", op, to)
+	}
+
+	doc, err := dec.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, from, err)
+	}
+	res, err := enc.encode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, to, err)
+	}
+	return res, nil
+}
+
+// groups returns the names of obj in order of first use, each with the
+// fields that share it.
+func (obj object) groups() (names []string, byName map[string][]field) {
+	byName = make(map[string][]field)
+	for _, f := range obj {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	return names, byName
+}
+
+func decodeGurlf(data []byte) (object, error) {
+	f, err := ast.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return fromNodes(f.Nodes), nil
+}
+
+func fromNodes(nodes []ast.Node) object {
+	obj := object{}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.Entry:
+			obj = append(obj, field{name: n.Key(), value: string(n.Value())})
+		case *ast.Section:
+			obj = append(obj, field{name: n.Name, obj: fromNodes(n.Nodes)})
+		}
+	}
+	return obj
+}
+
+func encodeGurlf(doc object) ([]byte, error) {
+	var dst []byte
+	for i, f := range doc {
+		if f.obj == nil {
+			return nil, fmt.Errorf("top-level key %q: %w", f.name, ErrUnsupported)
+		}
+		if i > 0 {
+			dst = append(dst, '\n')
+		}
+
+		var err error
+		if dst, err = appendSection(dst, f); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func appendSection(dst []byte, sec field) ([]byte, error) {
+	if !core.ValidName(sec.name) {
+		return nil, fmt.Errorf("section name %q: %w", sec.name, ErrUnsupported)
+	}
+
+	dst = append(dst, '[')
+	dst = append(dst, sec.name...)
+	dst = append(dst, "]\n"...)
+	for _, f := range sec.obj {
+		if f.obj != nil {
+			var err error
+			if dst, err = appendSection(dst, f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !core.ValidKey(f.name) {
+			return nil, fmt.Errorf("key %q in section %q: %w", f.name, sec.name, ErrUnsupported)
+		}
+		dst = append(dst, f.name...)
+		dst = append(dst, ':')
+		if f.value != "" {
+			dst = append(dst, ' ')
+			dst = core.AppendText(dst, f.value)
+		}
+		dst = append(dst, '\n')
+	}
+	dst = append(dst, "[\\"...)
+	dst = append(dst, sec.name...)
+	return append(dst, "]\n"...), nil
+}
@@ -0,0 +1,106 @@
+package convert
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const doc = "[login]\n" +
+	"ID: 1\n" +
+	"TAG: a\n" +
+	"TAG: b\n" +
+	"BODY: ``\n{\n  \"user\": \"dev\"\n}\n``\n" +
+	"EMPTY:\n" +
+	"LEAD: `\n\tindented\n  text \n`\n" +
+	"TABS: ``\n\tx\ny'\n``\n" +
+	"TRAIL: `a \n\nb\n\n`\n" +
+	"[auth]\n" +
+	"USER: dev\n" +
+	"[\\auth]\n" +
+	"[\\login]\n" +
+	"\n" +
+	"[step]\n" +
+	"N: 1\n" +
+	"[\\step]\n" +
+	"\n" +
+	"[step]\n" +
+	"N: 2\n" +
+	"[\\step]\n"
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range Formats {
+		out, err := Convert([]byte(doc), "gurlf", format)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+
+		back, err := Convert(out, format, "gurlf")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v\n%s", format, err, out)
+		}
+		if string(back) != doc {
+			t.Errorf("%s: round trip mismatch\n%s\ngot\n%s", format, out, back)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	out, err := Convert([]byte(doc), "gurlf", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		`"TAG": [`,
+		`"BODY": "{\n  \"user\": \"dev\"\n}"`,
+		`"EMPTY": ""`,
+		`"step": [`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %s in\n%s", want, out)
+		}
+	}
+
+	in := `{"req": {"ID": 7, "OK": true, "NONE": null, "MULTI": "a\nb", "LIST": ["x", {"K": "v"}]}}`
+	got, err := Convert([]byte(in), "json", "gurlf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[req]\nID: 7\nOK: true\nNONE:\nMULTI: ``\na\nb\n``\nLIST: x\n[LIST]\nK: v\n[\\LIST]\n[\\req]\n"
+	if string(got) != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, got)
+	}
+
+	for _, in := range []string{`{"a": {}} garbage`, `{"a": {}} {`, `{"a": {}} {"b": {}}`, `{"a": {}`} {
+		if _, err := Convert([]byte(in), "json", "gurlf"); err == nil {
+			t.Errorf("expected error for %s", in)
+		}
+	}
+	if _, err := Convert([]byte("{\"a\": {}}\n\t "), "json", "gurlf"); err != nil {
+		t.Errorf("unexpected error for trailing space: %v", err)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	tests := []struct {
+		in, from, to string
+	}{
+		{`{"a": "top-level key"}`, "json", "gurlf"},
+		{`{"a": {"B": [["nested"]]}}`, "json", "gurlf"},
+		{`{"a": {"B:C": "x"}}`, "json", "gurlf"},
+		{`["not", "an", "object"]`, "json", "gurlf"},
+		{"[a]\nK: v\n[K]\n[\\K]\n[\\a]\n", "gurlf", "toml"},
+	}
+
+	for i, tt := range tests {
+		_, err := Convert([]byte(tt.in), tt.from, tt.to)
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("[%d]: expected ErrUnsupported, got %v", i, err)
+		}
+	}
+
+	if _, err := Convert(nil, "xml", "gurlf"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,158 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+func decodeJSON(data []byte) (object, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("top-level value: %w", ErrUnsupported)
+	}
+	doc, err := jsonObject(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, errors.New("trailing data after top-level value")
+	} else if err != io.EOF {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// jsonObject reads the members of an object whose '{' was consumed.
+func jsonObject(dec *json.Decoder) (object, error) {
+	obj := object{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := tok.(string)
+
+		if obj, err = jsonValue(dec, obj, name, true); err != nil {
+			return nil, err
+		}
+	}
+	_, err := dec.Token()
+	return obj, err
+}
+
+// jsonValue reads one value and appends it to obj as name. Arrays turn
+// into one field per element and may not nest.
+func jsonValue(dec *json.Decoder, obj object, name string, arrays bool) (object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			sub, err := jsonObject(dec)
+			if err != nil {
+				return nil, err
+			}
+			return append(obj, field{name: name, obj: sub}), nil
+		case '[':
+			if !arrays {
+				return nil, fmt.Errorf("nested array %q: %w", name, ErrUnsupported)
+			}
+			for dec.More() {
+				if obj, err = jsonValue(dec, obj, name, false); err != nil {
+					return nil, err
+				}
+			}
+			_, err := dec.Token()
+			return obj, err
+		}
+	case string:
+		return append(obj, field{name: name, value: tok}), nil
+	case json.Number:
+		return append(obj, field{name: name, value: tok.String()}), nil
+	case bool:
+		return append(obj, field{name: name, value: fmt.Sprint(tok)}), nil
+	case nil:
+		return append(obj, field{name: name}), nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+func encodeJSON(doc object) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, doc, ""); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, obj object, indent string) error {
+	names, byName := obj.groups()
+	if len(names) == 0 {
+		buf.WriteString("{}")
+		return nil
+	}
+
+	buf.WriteString("{\n")
+	for i, name := range names {
+		buf.WriteString(indent + "  ")
+		writeString(buf, name)
+		buf.WriteString(": ")
+
+		fs := byName[name]
+		if len(fs) == 1 {
+			if err := writeJSONField(buf, fs[0], indent+"  "); err != nil {
+				return err
+			}
+		} else {
+			buf.WriteString("[\n")
+			for j, f := range fs {
+				buf.WriteString(indent + "    ")
+				if err := writeJSONField(buf, f, indent+"    "); err != nil {
+					return err
+				}
+				if j < len(fs)-1 {
+					buf.WriteByte(',')
+				}
+				buf.WriteByte('\n')
+			}
+			buf.WriteString(indent + "  ]")
+		}
+
+		if i < len(names)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(indent + "}")
+	return nil
+}
+
+func writeJSONField(buf *bytes.Buffer, f field, indent string) error {
+	if f.obj != nil {
+		return writeJSON(buf, f.obj, indent)
+	}
+	writeString(buf, f.value)
+	return nil
+}
+
+// writeString writes s as a JSON string without HTML escaping.
+func writeString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,186 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func decodeTOML(data []byte) (object, error) {
+	var m map[string]any
+	md, err := toml.Decode(string(data), &m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tables come back as maps, so recover the file order from the keys
+	// the decoder saw.
+	order := make(map[string]int)
+	for i, k := range md.Keys() {
+		p := strings.Join(k, "\x00")
+		if _, ok := order[p]; !ok {
+			order[p] = i
+		}
+	}
+	return tomlObject(m, "", order)
+}
+
+func tomlObject(m map[string]any, path string, order map[string]int) (object, error) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if d := order[path+a] - order[path+b]; d != 0 {
+			return d
+		}
+		return strings.Compare(a, b)
+	})
+
+	obj := object{}
+	for _, name := range names {
+		var err error
+		if obj, err = tomlValue(obj, name, m[name], path+name+"\x00", order, true); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func tomlValue(obj object, name string, v any, path string, order map[string]int, arrays bool) (object, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		sub, err := tomlObject(v, path, order)
+		if err != nil {
+			return nil, err
+		}
+		return append(obj, field{name: name, obj: sub}), nil
+	case []map[string]any:
+		for _, el := range v {
+			sub, err := tomlObject(el, path, order)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, field{name: name, obj: sub})
+		}
+		return obj, nil
+	case []any:
+		if !arrays {
+			return nil, fmt.Errorf("nested array %q: %w", name, ErrUnsupported)
+		}
+		for _, el := range v {
+			var err error
+			if obj, err = tomlValue(obj, name, el, path, order, false); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case string:
+		return append(obj, field{name: name, value: v}), nil
+	case time.Time:
+		return append(obj, field{name: name, value: v.Format(time.RFC3339Nano)}), nil
+	}
+	return append(obj, field{name: name, value: fmt.Sprint(v)}), nil
+}
+
+func encodeTOML(doc object) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeTable(&buf, "", doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(buf.Bytes(), []byte("\n")), nil
+}
+
+// writeTable writes the keys of obj, then its sections as sub-tables.
+// TOML needs keys before tables, so a section that came before a key in
+// gurlf moves after it.
+func writeTable(buf *bytes.Buffer, path string, obj object) error {
+	names, byName := obj.groups()
+
+	var tables []string
+	for _, name := range names {
+		fs := byName[name]
+		sections := 0
+		for _, f := range fs {
+			if f.obj != nil {
+				sections++
+			}
+		}
+		if sections == len(fs) {
+			tables = append(tables, name)
+			continue
+		} else if sections > 0 {
+			return fmt.Errorf("%q mixes keys and sections: %w", name, ErrUnsupported)
+		}
+
+		buf.WriteString(tomlKey(name))
+		buf.WriteString(" = ")
+		if len(fs) == 1 {
+			writeTOMLString(buf, fs[0].value)
+		} else {
+			buf.WriteByte('[')
+			for i, f := range fs {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				writeTOMLString(buf, f.value)
+			}
+			buf.WriteByte(']')
+		}
+		buf.WriteByte('\n')
+	}
+
+	for _, name := range tables {
+		sub := path + tomlKey(name)
+		fs := byName[name]
+		for _, f := range fs {
+			if len(fs) == 1 {
+				fmt.Fprintf(buf, "\n[%s]\n", sub)
+			} else {
+				fmt.Fprintf(buf, "\n[[%s]]\n", sub)
+			}
+			if err := writeTable(buf, sub+".", f.obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tomlKey(name string) string {
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			var buf bytes.Buffer
+			writeString(&buf, name)
+			return buf.String()
+		}
+	}
+	if name == "" {
+		return `""`
+	}
+	return name
+}
+
+// writeTOMLString writes multiline values as literal blocks where TOML
+// allows it and as escaped basic strings otherwise. JSON string escapes
+// are a subset of TOML ones.
+func writeTOMLString(buf *bytes.Buffer, s string) {
+	literal := strings.Contains(s, "\n") && !strings.Contains(s, "'''") && !strings.HasSuffix(s, "'")
+	for i := 0; literal && i < len(s); i++ {
+		if c := s[i]; c < 0x20 && c != '\t' && c != '\n' || c == 0x7f {
+			literal = false
+		}
+	}
+
+	if literal {
+		buf.WriteString("'''\n")
+		buf.WriteString(s)
+		buf.WriteString("'''")
+		return
+	}
+	writeString(buf, s)
+}
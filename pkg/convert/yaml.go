@@ -0,0 +1,118 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func decodeYAML(data []byte) (object, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return object{}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("top-level value: %w", ErrUnsupported)
+	}
+	return yamlObject(root)
+}
+
+func yamlObject(n *yaml.Node) (object, error) {
+	obj := object{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		var err error
+		if obj, err = yamlValue(obj, n.Content[i].Value, n.Content[i+1], true); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func yamlValue(obj object, name string, n *yaml.Node, arrays bool) (object, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		sub, err := yamlObject(n)
+		if err != nil {
+			return nil, err
+		}
+		return append(obj, field{name: name, obj: sub}), nil
+	case yaml.SequenceNode:
+		if !arrays {
+			return nil, fmt.Errorf("nested array %q: %w", name, ErrUnsupported)
+		}
+		for _, el := range n.Content {
+			var err error
+			if obj, err = yamlValue(obj, name, el, false); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return append(obj, field{name: name}), nil
+		}
+		return append(obj, field{name: name, value: n.Value}), nil
+	}
+	return nil, fmt.Errorf("value %q: %w", name, ErrUnsupported)
+}
+
+func encodeYAML(doc object) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(doc)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func yamlNode(obj object) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	names, byName := obj.groups()
+	for _, name := range names {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+
+		fs := byName[name]
+		if len(fs) == 1 {
+			n.Content = append(n.Content, key, yamlField(fs[0]))
+			continue
+		}
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, f := range fs {
+			seq.Content = append(seq.Content, yamlField(f))
+		}
+		n.Content = append(n.Content, key, seq)
+	}
+	return n
+}
+
+func yamlField(f field) *yaml.Node {
+	if f.obj != nil {
+		return yamlNode(f.obj)
+	}
+
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.value}
+	// Literal blocks lose a leading line break and cannot start with
+	// indentation, so such values are quoted instead.
+	if strings.Contains(f.value, "\n") {
+		n.Style = yaml.LiteralStyle
+		if strings.IndexByte(" \t\n", f.value[0]) != -1 {
+			n.Style = yaml.DoubleQuotedStyle
+		}
+	}
+	return n
+}
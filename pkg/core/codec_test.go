@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+type method uint8
+
+func (m *method) UnmarshalGurlf(b []byte) error {
+	switch strings.ToUpper(string(b)) {
+	case "GET":
+		*m = 1
+	case "POST":
+		*m = 2
+	default:
+		return errors.New("unknown method")
+	}
+	return nil
+}
+
+func (m method) MarshalGurlf() ([]byte, error) {
+	return []byte([]string{"", "GET", "POST"}[m]), nil
+}
+
+type level string
+
+func (l *level) UnmarshalText(b []byte) error {
+	*l = level(strings.ToLower(string(b)))
+	return nil
+}
+
+func (l level) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(string(l))), nil
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	type config struct {
+		Name    string        `gurlf:"config_name"`
+		Method  method        `gurlf:"METHOD"`
+		Level   level         `gurlf:"LEVEL"`
+		Addr    netip.Addr    `gurlf:"ADDR"`
+		Created time.Time     `gurlf:"CREATED"`
+		Timeout time.Duration `gurlf:"TIMEOUT"`
+		URL     url.URL       `gurlf:"URL"`
+		Proxy   *url.URL      `gurlf:"PROXY"`
+		Retries *int          `gurlf:"RETRIES"`
+	}
+
+	raw := []byte("[cfg]\nMETHOD: post\nLEVEL: Debug\nADDR: 10.0.0.1\n" +
+		"CREATED: 2024-05-01T10:00:00Z\nTIMEOUT: 1m30s\nURL: https://example.com/a?b=c\n" +
+		"PROXY: http://proxy:8080\nRETRIES: 3\n[\\cfg]\n")
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got config
+	if err := Unmarshal(ds[0], &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		expected string
+		actual   string
+	}{
+		{"POST", string(must(got.Method.MarshalGurlf()))},
+		{"debug", string(got.Level)},
+		{"10.0.0.1", got.Addr.String()},
+		{"2024-05-01T10:00:00Z", got.Created.Format(time.RFC3339)},
+		{"1m30s", got.Timeout.String()},
+		{"https://example.com/a?b=c", got.URL.String()},
+		{"http://proxy:8080", got.Proxy.String()},
+		{"3", strconv.Itoa(*got.Retries)},
+	}
+	for i, tt := range tests {
+		if tt.actual != tt.expected {
+			t.Errorf("[%d]: expected %q, got %q", i, tt.expected, tt.actual)
+		}
+	}
+
+	b, err := Marshal(got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[cfg]\nMETHOD:POST\nLEVEL:DEBUG\nADDR:10.0.0.1\nCREATED:2024-05-01T10:00:00Z\n" +
+		"TIMEOUT:1m30s\nURL:https://example.com/a?b=c\nPROXY:http://proxy:8080\nRETRIES:3\n[\\cfg]\n\n"
+	if string(b) != want {
+		t.Errorf("\n Got: %q\nWant: %q", b, want)
+	}
+}
+
+func TestCodecError(t *testing.T) {
+	type config struct {
+		Method method `gurlf:"METHOD"`
+	}
+	data := scanner.Data{
+		RawData: []byte("METHOD: PUT\n"),
+		Entries: []scanner.Entry{{KeyStart: 0, KeyEnd: 6, ValStart: 8, ValEnd: 11}},
+	}
+
+	var cfg config
+	if err := Unmarshal(data, &cfg); err == nil {
+		t.Errorf("expected error for unknown method")
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestUnmarshalAllocs(t *testing.T) {
+	raw := []byte("[config]\nID:12\nUser:admin\n[\\config]")
+	d := scanner.Data{
+		Name:    []byte("config"),
+		RawData: raw,
+		Entries: []scanner.Entry{
+			{KeyStart: 9, KeyEnd: 11, ValStart: 12, ValEnd: 14},
+			{KeyStart: 15, KeyEnd: 19, ValStart: 20, ValEnd: 25},
+		},
+	}
+	type cfg struct {
+		ID   int    `gurlf:"ID"`
+		User string `gurlf:"User"`
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		var c cfg
+		if err := Unmarshal(d, &c); err != nil || c.ID != 12 || c.User != "admin" {
+			t.Fatalf("unexpected result: %+v, %v", c, err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
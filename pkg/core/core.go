@@ -15,19 +15,38 @@ import (
 )
 
 type field struct {
-	tag []byte
-	idx []int
+	tag      []byte
+	idx      []int
+	codec    codec
+	required bool
+	def      []byte
+	list     bool
+	// expand interpolates backtick blocks as well as plain values.
+	expand bool
+	// next is the following unmFields entry with the same tag, or -1.
+	next int
 }
 type marshalField struct {
 	precomputedTag []byte
 	idx            []int
 	isConfigName   bool
 	omitempty      bool
+	codec          codec
+	list           bool
 }
 type structCache struct {
 	unmFields []field
 	marFields []marshalField
+	secFields []field
+	keys      dispatch
 	nameIdx   []int
+	// remain is the ",remain" map field collecting unclaimed keys.
+	remain field
+	// presence lists the unmFields that are required or have a default.
+	presence []int
+	// generated, inPlace and appender report whether *T implements
+	// SectionUnmarshaler, Generated and SectionAppender.
+	generated, inPlace, appender bool
 }
 
 var (
@@ -39,26 +58,64 @@ var (
 	}
 )
 
+// UnmarshalOptions configures how sections are decoded.
+// The zero value ignores unknown keys, lets the last duplicate win and
+// keeps values as written.
+type UnmarshalOptions struct {
+	// DisallowUnknownKeys rejects keys that no field is tagged with.
+	DisallowUnknownKeys bool
+	// DisallowDuplicateKeys rejects keys repeated within a section.
+	DisallowDuplicateKeys bool
+	// Expand replaces ${...} references in values. Backtick blocks are
+	// kept as written unless their field is tagged ",expand". A value
+	// that would expand to more than 1 MiB fails with ErrExpandTooLarge.
+	Expand bool
+	// Resolver supplies references that are not keys of the input.
+	// Nil means EnvResolver.
+	Resolver Resolver
+
+	// x expands the values of one Unmarshal or UnmarshalAll call.
+	x *expander
+}
+
 func Unmarshal(d scanner.Data, v any) error {
+	return UnmarshalOptions{}.Unmarshal(d, v)
+}
+
+// Unmarshal is like the package-level Unmarshal, but reports a *KeyError
+// for keys rejected by o.
+func (o UnmarshalOptions) Unmarshal(d scanner.Data, v any) error {
 	const op = "core.Unmarshal"
 
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
 		return fmt.Errorf("%s: invalid value: need pointer to value", op)
 	}
-	rv = rv.Elem()
-	rt := rv.Type()
+	if o.Expand {
+		o.x = newExpander(o.Resolver, nil)
+	}
 
-	var info structCache
-	if val, ok := cache.Load(rt); ok {
-		info = val.(structCache)
-	} else {
-		info.unmFields = make([]field, 0, rt.NumField())
-		fillCache(rt, &info, nil)
-		cache.Store(rt, info)
+	return o.unmarshal(d, rv.Elem())
+}
+
+func (o UnmarshalOptions) unmarshal(d scanner.Data, rv reflect.Value) error {
+	const op = "core.Unmarshal"
+
+	if rv.Kind() == reflect.Map {
+		if err := o.unmarshalMap(d, rv); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
 	}
 
-	if len(info.unmFields) == 0 {
+	info := loadCache(rv.Type())
+	if info.generated && !o.DisallowUnknownKeys && !o.DisallowDuplicateKeys && !o.Expand {
+		if err := unmarshalGenerated(d, rv, info.inPlace); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+	if len(info.unmFields) == 0 && len(info.secFields) == 0 && info.remain.idx == nil {
 		return fmt.Errorf("%s: unmFields unmFields: zero unmFields", op)
 	}
 
@@ -68,25 +125,279 @@ func Unmarshal(d scanner.Data, v any) error {
 		}
 	}
 
-	for _, ent := range d.Entries {
+	for i, ent := range d.Entries {
 		key := d.RawData[ent.KeyStart:ent.KeyEnd]
 		if len(key) == 0 {
 			continue
 		}
 		val := d.RawData[ent.ValStart:ent.ValEnd]
 
-		for _, f := range info.unmFields {
-			if bytes.Equal(f.tag, key) {
-				if err := setValue(rv.FieldByIndex(f.idx), val); err != nil {
-					return fmt.Errorf("%s: set value: %w", op, err)
+		known, list := false, false
+		for fi := info.keys.lookup(info.unmFields, key); fi >= 0; fi = info.unmFields[fi].next {
+			f := info.unmFields[fi]
+			known = true
+
+			val := val
+			if o.Expand {
+				var err error
+				if val, err = o.expandEntry(d, ent, f.expand); err != nil {
+					return fmt.Errorf("%s: %w", op, err)
+				}
+			}
+
+			fv := rv.FieldByIndex(f.idx)
+			if f.list {
+				list = true
+				if !repeated(d, i) {
+					fv.SetLen(0)
+				}
+				fv = grow(fv)
+			}
+			if err := setField(fv, val, f.codec); err != nil {
+				return fmt.Errorf("%s: set value: %w", op, err)
+			}
+		}
+		if !known && info.remain.idx != nil {
+			known, list = true, info.remain.list
+			if !list && o.DisallowDuplicateKeys && repeated(d, i) {
+				return fmt.Errorf("%s: %w", op, keyError(d, ent, ErrDuplicateKey))
+			}
+			if o.Expand {
+				var err error
+				if val, err = o.expandEntry(d, ent, false); err != nil {
+					return fmt.Errorf("%s: %w", op, err)
 				}
 			}
+			err := setMapEntry(rv.FieldByIndex(info.remain.idx), key, val,
+				info.remain.codec, list, !repeated(d, i))
+			if err != nil {
+				return fmt.Errorf("%s: set value: %w", op, err)
+			}
+		}
+		if !known && o.DisallowUnknownKeys {
+			return fmt.Errorf("%s: %w", op, keyError(d, ent, ErrUnknownKey))
+		}
+		if !list && o.DisallowDuplicateKeys && repeated(d, i) {
+			return fmt.Errorf("%s: %w", op, keyError(d, ent, ErrDuplicateKey))
+		}
+	}
+
+	for _, i := range info.presence {
+		f := info.unmFields[i]
+		if hasKey(d, f.tag) {
+			continue
+		}
+		if f.required {
+			return fmt.Errorf("%s: %w", op, &KeyError{
+				Section: string(d.Name),
+				Key:     string(f.tag),
+				Line:    d.Line,
+				Err:     ErrMissingKey,
+			})
+		}
+		fv := rv.FieldByIndex(f.idx)
+		if f.list {
+			fv.SetLen(0)
+			fv = grow(fv)
+		}
+		def := f.def
+		if o.Expand {
+			var err error
+			if def, err = o.expandDefault(d, def); err != nil {
+				return fmt.Errorf("%s: expand default %q: %w", op, f.tag, err)
+			}
+		}
+		if err := setField(fv, def, f.codec); err != nil {
+			return fmt.Errorf("%s: set default %q: %w", op, f.tag, err)
+		}
+	}
+
+	if len(d.Children) > 0 {
+		if err := o.unmarshalChildren(d.Children, rv, info); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
 		}
 	}
 
 	return nil
 }
 
+func hasKey(d scanner.Data, key []byte) bool {
+	for _, ent := range d.Entries {
+		if bytes.Equal(d.RawData[ent.KeyStart:ent.KeyEnd], key) {
+			return true
+		}
+	}
+	return false
+}
+
+// repeated reports whether the key of d.Entries[i] appears earlier in d.
+func repeated(d scanner.Data, i int) bool {
+	ent := d.Entries[i]
+	key := d.RawData[ent.KeyStart:ent.KeyEnd]
+	for _, prev := range d.Entries[:i] {
+		if bytes.Equal(d.RawData[prev.KeyStart:prev.KeyEnd], key) {
+			return true
+		}
+	}
+	return false
+}
+
+func keyError(d scanner.Data, ent scanner.Entry, err error) *KeyError {
+	return &KeyError{
+		Section: string(d.Name),
+		Key:     string(d.RawData[ent.KeyStart:ent.KeyEnd]),
+		Line:    d.LineOf(ent.KeyStart),
+		Err:     err,
+	}
+}
+
+// UnmarshalAll stores every section of ds in v, which must be a pointer to
+// a slice of structs, a map from section name to struct, or a struct whose
+// fields are tagged with section names. Section fields may be T, *T, []T,
+// []*T or a string-keyed map holding the section's keys; sections without
+// a matching field are skipped.
+func UnmarshalAll(ds []scanner.Data, v any) error {
+	return UnmarshalOptions{}.UnmarshalAll(ds, v)
+}
+
+// UnmarshalAll is like the package-level UnmarshalAll, but applies o to
+// every section.
+func (o UnmarshalOptions) UnmarshalAll(ds []scanner.Data, v any) error {
+	const op = "core.UnmarshalAll"
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%s: invalid value: need pointer to value", op)
+	}
+	rv = rv.Elem()
+	if o.Expand {
+		o.x = newExpander(o.Resolver, ds)
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice:
+		rv.SetLen(0)
+		for _, d := range ds {
+			if err := o.unmarshalSection(d, rv); err != nil {
+				return fmt.Errorf("%s: section %q: %w", op, d.Name, err)
+			}
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: invalid value: need string map key, but got %q",
+				op, rv.Type().Key().Kind())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(ds)))
+		}
+		for _, d := range ds {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := o.unmarshalSection(d, elem); err != nil {
+				return fmt.Errorf("%s: section %q: %w", op, d.Name, err)
+			}
+			rv.SetMapIndex(reflect.ValueOf(string(d.Name)).Convert(rv.Type().Key()), elem)
+		}
+	case reflect.Struct:
+		if err := o.unmarshalChildren(ds, rv, loadCache(rv.Type())); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	default:
+		return fmt.Errorf("%s: invalid value: need slice, map or struct, but got %q",
+			op, rv.Kind())
+	}
+
+	return nil
+}
+
+func (o UnmarshalOptions) unmarshalChildren(ds []scanner.Data, rv reflect.Value, info structCache) error {
+	seen := make([]bool, len(info.secFields))
+	for _, d := range ds {
+		for i, f := range info.secFields {
+			if !bytes.Equal(f.tag, d.Name) {
+				continue
+			}
+
+			fv := rv.FieldByIndex(f.idx)
+			if fv.Kind() == reflect.Slice && !seen[i] {
+				fv.SetLen(0)
+			}
+			seen[i] = true
+
+			if err := o.unmarshalSection(d, fv); err != nil {
+				return fmt.Errorf("section %q: %w", d.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// grow appends a zero element to the slice v and returns it.
+func grow(v reflect.Value) reflect.Value {
+	n := v.Len()
+	if n == v.Cap() {
+		grown := reflect.MakeSlice(v.Type(), n, 2*n+1)
+		reflect.Copy(grown, v)
+		assign(v, grown)
+	}
+	v.SetLen(n + 1)
+	v.Index(n).SetZero()
+	return v.Index(n)
+}
+
+func (o UnmarshalOptions) unmarshalSection(d scanner.Data, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		return o.unmarshal(d, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			assign(v, reflect.New(v.Type().Elem()))
+		}
+		return o.unmarshalSection(d, v.Elem())
+	case reflect.Slice:
+		return o.unmarshalSection(d, grow(v))
+	}
+
+	return fmt.Errorf("unsupported section type: %v", v.Type())
+}
+
+// isList reports whether fields of type t collect every occurrence of
+// their key. Byte slices and types with their own codec hold one value.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 &&
+		unmarshalCodec(t) == codecNone
+}
+
+func isSection(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Map {
+		return t.Key().Kind() == reflect.String
+	}
+	return t.Kind() == reflect.Struct && unmarshalCodec(t) == codecNone
+}
+
+func loadCache(rt reflect.Type) structCache {
+	if val, ok := cache.Load(rt); ok {
+		return val.(structCache)
+	}
+
+	var info structCache
+	info.unmFields = make([]field, 0, rt.NumField())
+	fillCache(rt, &info, nil)
+	info.keys = newDispatch(info.unmFields)
+	info.generated = reflect.PointerTo(rt).Implements(sectionUnmarshalerType)
+	info.inPlace = reflect.PointerTo(rt).Implements(generatedType)
+	info.appender = reflect.PointerTo(rt).Implements(sectionAppenderType)
+	cache.Store(rt, info)
+
+	return info
+}
+
 func fillCache(rt reflect.Type, info *structCache, path []int) {
 	for i := range rt.NumField() {
 		f := rt.Field(i)
@@ -98,8 +409,9 @@ func fillCache(rt reflect.Type, info *structCache, path []int) {
 			continue
 		}
 
-		tag, omitempty := parseTag(f.Tag.Get("gurlf"))
-		if tag == "" {
+		opts := parseTag(f.Tag.Get("gurlf"))
+		tag, omitempty := opts.name, opts.omitempty
+		if tag == "" && !opts.remain {
 			continue
 		}
 		path = append(path, i)
@@ -107,6 +419,28 @@ func fillCache(rt reflect.Type, info *structCache, path []int) {
 		finalIdx := make([]int, len(path))
 		copy(finalIdx, path)
 
+		if opts.remain {
+			if f.Type.Kind() == reflect.Map && f.Type.Key().Kind() == reflect.String {
+				et := f.Type.Elem()
+				list := isList(et)
+				if list {
+					et = et.Elem()
+				}
+				info.remain = field{idx: finalIdx, codec: unmarshalCodec(et), list: list}
+			}
+			path = path[:len(path)-1]
+			continue
+		}
+
+		if isSection(f.Type) {
+			info.secFields = append(info.secFields, field{
+				tag: []byte(tag),
+				idx: finalIdx,
+			})
+			path = path[:len(path)-1]
+			continue
+		}
+
 		if tag == "config_name" {
 			info.nameIdx = finalIdx
 			info.marFields = append(info.marFields, marshalField{
@@ -118,9 +452,22 @@ func fillCache(rt reflect.Type, info *structCache, path []int) {
 			continue
 		}
 
+		list, ft := isList(f.Type), f.Type
+		if list {
+			ft = ft.Elem()
+		}
+
+		if opts.required || opts.def != nil {
+			info.presence = append(info.presence, len(info.unmFields))
+		}
 		info.unmFields = append(info.unmFields, field{
-			tag: []byte(tag),
-			idx: finalIdx,
+			tag:      []byte(tag),
+			idx:      finalIdx,
+			codec:    unmarshalCodec(ft),
+			required: opts.required,
+			def:      opts.def,
+			list:     list,
+			expand:   opts.expand,
 		})
 
 		prep := make([]byte, 0, len(tag)+1)
@@ -130,24 +477,56 @@ func fillCache(rt reflect.Type, info *structCache, path []int) {
 			precomputedTag: prep,
 			idx:            finalIdx,
 			omitempty:      omitempty,
+			codec:          marshalCodec(ft),
+			list:           list,
 		})
 
 		path = path[:len(path)-1]
 	}
 }
 
-func parseTag(tag string) (string, bool) {
-	omitempty := false
-	parts := strings.Split(tag, ",")
-	if len(parts) == 0 {
-		return "", false
-	}
+type tagOptions struct {
+	name      string
+	omitempty bool
+	required  bool
+	remain    bool
+	expand    bool
+	def       []byte
+}
+
+// parseTag splits a gurlf tag into its options. A default takes the rest
+// of the tag, so "default=" must come last and its value may hold commas.
+func parseTag(tag string) tagOptions {
+	name, rest, _ := strings.Cut(tag, ",")
+	opts := tagOptions{name: name}
+	for rest != "" {
+		if def, ok := strings.CutPrefix(rest, "default="); ok {
+			opts.def = []byte(def)
+			break
+		}
 
-	if slices.Contains(parts, "omitempty") {
-		omitempty = true
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+		switch opt {
+		case "omitempty":
+			opts.omitempty = true
+		case "required":
+			opts.required = true
+		case "remain":
+			opts.remain = true
+		case "expand":
+			opts.expand = true
+		}
 	}
 
-	return parts[0], omitempty
+	return opts
+}
+
+func setField(v reflect.Value, val []byte, c codec) error {
+	if c != codecNone {
+		return setCustom(v, val, c)
+	}
+	return setValue(v, val)
 }
 
 func setValue(v reflect.Value, val []byte) error {
@@ -157,17 +536,49 @@ func setValue(v reflect.Value, val []byte) error {
 		return nil
 	}
 
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), val); err != nil {
+			return err
+		}
+		assign(v, p)
+		return nil
+	}
+
+	str := unsafe.String(unsafe.SliceData(val), len(val))
 	switch v.Kind() {
 	case reflect.String:
-		str := unsafe.String(unsafe.SliceData(val), len(val))
 		v.SetString(str)
-	case reflect.Int, reflect.Int32, reflect.Int64:
-		str := unsafe.String(unsafe.SliceData(val), len(val))
-		i, err := strconv.ParseInt(str, 10, 64)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return fmt.Errorf("%s: cannot parse bool: %w", op, err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 10, v.Type().Bits())
 		if err != nil {
 			return fmt.Errorf("%s: cannot parse int: %w", op, err)
 		}
 		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(str, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: cannot parse uint: %w", op, err)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: cannot parse float: %w", op, err)
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(str, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: cannot parse complex: %w", op, err)
+		}
+		v.SetComplex(c)
 	case reflect.Slice:
 		if v.Type().Elem().Kind() == reflect.Uint8 {
 			v.SetBytes(val)
@@ -179,6 +590,10 @@ func setValue(v reflect.Value, val []byte) error {
 	return nil
 }
 
+// Marshal encodes v as gurlf. A struct becomes a single section, named by
+// its config_name field; a struct without one is written as bare keys
+// followed by its section fields. Slices of structs and maps from section
+// name to struct produce one section per element, maps in key order.
 func Marshal(v any) ([]byte, error) {
 	const op = "core.Marshal"
 
@@ -187,36 +602,87 @@ func Marshal(v any) ([]byte, error) {
 		rv = rv.Elem()
 	}
 
-	if rv.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("%s: invalid value: need struct, but got %q",
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+
+	var res []byte
+	var err error
+	switch {
+	case rv.Kind() == reflect.Struct:
+		res, err = appendSection(buf.Bytes(), nil, rv, true)
+	case rv.Kind() == reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: invalid value: need string map key, but got %q",
+				op, rv.Type().Key().Kind())
+		}
+		if isSection(rv.Type().Elem()) {
+			res, err = appendMap(buf.Bytes(), rv)
+		} else {
+			res, err = appendMapEntries(buf.Bytes(), rv)
+		}
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && isSection(rv.Type()):
+		res, err = appendSections(buf.Bytes(), nil, rv, true)
+	default:
+		return nil, fmt.Errorf("%s: invalid value: need struct, slice or map, but got %q",
 			op, rv.Kind())
 	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-	rt := rv.Type()
-	var info structCache
-	if val, ok := cache.Load(rt); ok {
-		info = val.(structCache)
+	final := make([]byte, len(res))
+	copy(final, res)
+	return final, nil
+}
+
+// appendSection writes rv as a section. Nested sections are written inside
+// their parent; top-level ones are followed by a blank line.
+func appendSection(dst, name []byte, rv reflect.Value, top bool) ([]byte, error) {
+	info := loadCache(rv.Type())
+
+	nStart := len(dst) + 1
+	dst = append(dst, '[')
+	if name != nil {
+		dst = append(dst, name...)
+	} else if info.nameIdx != nil {
+		dst = appendValue(dst, rv.FieldByIndex(info.nameIdx))
+	}
+	nEnd := len(dst)
+	if nStart == nEnd {
+		dst = dst[:nStart-1]
+	} else if !validName(dst[nStart:nEnd]) {
+		return dst, fmt.Errorf("%w %q", ErrInvalidName, dst[nStart:nEnd])
 	} else {
-		fillCache(rt, &info, nil)
-		cache.Store(rt, info)
+		dst = append(dst, ']', '\n')
 	}
 
-	var cfgName []byte
-	if info.nameIdx != nil {
-		cfgName = appendValue(nil, rv.FieldByIndex(info.nameIdx))
+	var err error
+	if info.appender && nStart != nEnd {
+		dst = appendGenerated(dst, rv)
+	} else if dst, err = appendBody(dst, rv, info, nStart == nEnd); err != nil {
+		return dst, err
 	}
 
-	buf := bufferPool.Get().(*bytes.Buffer)
-	buf.Reset()
-	defer bufferPool.Put(buf)
+	if nStart == nEnd {
+		return dst, nil
+	}
 
-	res := buf.Bytes()
-	var nStart, nEnd int
-	for _, f := range info.marFields {
-		if rv.Kind() == reflect.Pointer {
-			rv = rv.Elem()
-		}
+	dst = append(dst, '[', '\\')
+	dst = append(dst, dst[nStart:nEnd]...)
+	dst = append(dst, ']', '\n')
+	if top {
+		dst = append(dst, '\n')
+	}
 
+	return dst, nil
+}
+
+// appendBody writes the keys and nested sections of rv. Without a header
+// the nested sections are written as top-level ones.
+func appendBody(dst []byte, rv reflect.Value, info structCache, headerless bool) ([]byte, error) {
+	var err error
+	for _, f := range info.marFields {
 		fV := rv.FieldByIndex(f.idx)
 		if f.isConfigName {
 			continue
@@ -226,76 +692,231 @@ func Marshal(v any) ([]byte, error) {
 			continue
 		}
 
-		res = append(res, f.precomputedTag...)
-		res = appendValue(res, fV)
-		res = append(res, '\n')
+		if !f.list {
+			if dst, err = appendField(dst, f, fV); err != nil {
+				return dst, err
+			}
+			continue
+		}
+		for i := range fV.Len() {
+			if dst, err = appendField(dst, f, fV.Index(i)); err != nil {
+				return dst, err
+			}
+		}
+	}
+
+	if info.remain.idx != nil {
+		if dst, err = appendMapEntries(dst, rv.FieldByIndex(info.remain.idx)); err != nil {
+			return dst, err
+		}
 	}
 
-	if len(cfgName) == 0 {
-		final := make([]byte, len(res))
-		copy(final, res)
-		return final, nil
+	for _, f := range info.secFields {
+		if dst, err = appendSections(dst, f.tag, rv.FieldByIndex(f.idx), headerless); err != nil {
+			return dst, err
+		}
 	}
 
-	finalSize := (len(res) - (nEnd - nStart)) + (len(cfgName) * 2) + 10
-	final := make([]byte, 0, finalSize)
+	return dst, nil
+}
 
-	final = append(final, '[')
-	final = append(final, cfgName...)
-	final = append(final, ']', '\n')
+func appendField(dst []byte, f marshalField, v reflect.Value) ([]byte, error) {
+	var err error
+	dst = append(dst, f.precomputedTag...)
+	if f.codec != codecNone {
+		if dst, err = appendCustom(dst, v, f.codec); err != nil {
+			return dst, err
+		}
+	} else {
+		dst = appendValue(dst, v)
+	}
+	return append(dst, '\n'), nil
+}
 
-	final = append(final, res...)
+func appendSections(dst, name []byte, v reflect.Value, top bool) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		return appendSection(dst, name, v, top)
+	case reflect.Map:
+		return appendMapSection(dst, name, v, top)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return dst, nil
+		}
+		return appendSections(dst, name, v.Elem(), top)
+	case reflect.Slice, reflect.Array:
+		var err error
+		for i := range v.Len() {
+			if dst, err = appendSections(dst, name, v.Index(i), top); err != nil {
+				return dst, err
+			}
+		}
+	}
+	return dst, nil
+}
 
-	final = append(final, '[', '\\')
-	final = append(final, cfgName...)
-	final = append(final, ']', '\n', '\n')
+func appendMap(dst []byte, v reflect.Value) ([]byte, error) {
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
 
-	return final, nil
+	var err error
+	for _, k := range keys {
+		if dst, err = appendSections(dst, []byte(k.String()), v.MapIndex(k), true); err != nil {
+			return dst, err
+		}
+	}
+	return dst, nil
 }
 
 func appendValue(dst []byte, v reflect.Value) []byte {
 	switch v.Kind() {
-	case reflect.String:
-		s := v.String()
-		if needMultiline(s) {
-			dst = append(dst, '`')
-			dst = append(dst, s...)
-			dst = append(dst, '`')
+	case reflect.Pointer:
+		if v.IsNil() {
 			return dst
 		}
-		return append(dst, s...)
+		return appendValue(dst, v.Elem())
+	case reflect.String:
+		return appendText(dst, v.String())
+	case reflect.Bool:
+		return strconv.AppendBool(dst, v.Bool())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return strconv.AppendInt(dst, v.Int(), 10)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.AppendInt(dst, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(dst, v.Uint(), 10)
+	case reflect.Complex64:
+		return append(dst, strconv.FormatComplex(v.Complex(), 'f', -1, 64)...)
+	case reflect.Complex128:
+		return append(dst, strconv.FormatComplex(v.Complex(), 'f', -1, 128)...)
 	case reflect.Float32:
 		return strconv.AppendFloat(dst, v.Float(), 'f', -1, 32)
 	case reflect.Float64:
 		return strconv.AppendFloat(dst, v.Float(), 'f', -1, 64)
 	case reflect.Slice:
 		b := v.Bytes()
-		s := unsafe.String(unsafe.SliceData(b), len(b))
-		if needMultiline(s) {
-			dst = append(dst, '`')
-			dst = append(dst, s...)
-			dst = append(dst, '`')
-			return dst
-		}
-		return append(dst, s...)
+		return appendText(dst, unsafe.String(unsafe.SliceData(b), len(b)))
 	}
 	return fmt.Append(dst, v.Interface())
 }
 
+// appendText writes s in a form the scanner reads back unchanged: bare
+// when possible, otherwise between single backticks, or inside a fence of
+// backticks longer than any backtick-only line of s.
+func appendText(dst []byte, s string) []byte {
+	if !needMultiline(s) {
+		return append(dst, s...)
+	}
+
+	if singleBlock(s) {
+		dst = append(dst, '`')
+		dst = append(dst, s...)
+		return append(dst, '`')
+	}
+
+	k := fenceLen(s)
+	for range k {
+		dst = append(dst, '`')
+	}
+	dst = append(dst, '\n')
+	dst = append(dst, s...)
+	dst = append(dst, '\n')
+	for range k {
+		dst = append(dst, '`')
+	}
+	return dst
+}
+
+// ValidKey reports whether key is written and scanned back as the same
+// key: it is not empty, has no surrounding spaces, holds no ':' or line
+// break and does not start with '#' or '['.
+func ValidKey(key string) bool { return validKey(key) }
+
+// ValidName reports whether name can be written as a section name: it
+// is not empty, holds no ']' or line break and does not start with '\'.
+func ValidName(name string) bool { return validName(name) }
+
+func validKey[S ~string | ~[]byte](key S) bool {
+	if len(key) == 0 || key[0] == '#' || key[0] == '[' ||
+		isSpace(key[0]) || isSpace(key[len(key)-1]) {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] == ':' || key[i] == '\n' || key[i] == '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func validName[S ~string | ~[]byte](name S) bool {
+	if len(name) == 0 || name[0] == '\\' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] == ']' || name[i] == '\n' || name[i] == '\r' {
+			return false
+		}
+	}
+	return true
+}
+
 func needMultiline(s string) bool {
+	if len(s) > 0 && s[0] == ' ' {
+		return true
+	}
 	for i := range len(s) {
 		switch s[i] {
 		case '\n', '\t', '\r', '`':
 			return true
+		case '[':
+			if i+1 < len(s) && s[i+1] == '\\' {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+func singleBlock(s string) bool {
+	nl := strings.IndexByte(s, '\n')
+	if nl == -1 {
+		return strings.Trim(s, "`") != ""
+	}
+	if s[len(s)-1] != '\n' || strings.IndexByte(s[:nl], '`') != -1 {
+		return false
+	}
+
+	for i := nl + 1; i < len(s)-1; i++ {
+		if s[i] == '`' && s[i-1] == '\n' && (s[i+1] == '\n' || s[i+1] == '\r') {
+			return false
+		}
+	}
+	return true
+}
+
+func fenceLen(s string) int {
+	k := 2
+	for i := 0; i < len(s); i++ {
+		if i > 0 && s[i-1] != '\n' {
+			continue
+		}
+
+		j := i
+		for j < len(s) && s[j] == '`' {
+			j++
+		}
+		if j > i && (j == len(s) || s[j] == '\n' || s[j] == '\r') {
+			k = max(k, j-i+1)
+		}
+	}
+	return k
+}
+
 func Encode(wr io.Writer, d []byte) error {
 	_, err := wr.Write(d)
 	return err
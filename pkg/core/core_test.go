@@ -2,10 +2,15 @@ package core
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/quick"
+	"time"
 
 	"github.com/Votline/Gurlf/pkg/scanner"
 )
@@ -92,6 +97,60 @@ func BenchmarkUnmarshal(b *testing.B) {
 	}
 }
 
+// wide builds a struct type with n string fields tagged K0..Kn-1 and a
+// section setting each of them, last key first.
+func wide(n int) (reflect.Type, scanner.Data) {
+	fields := make([]reflect.StructField, n)
+	var raw []byte
+	for i := range n {
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: reflect.TypeFor[string](),
+			Tag:  reflect.StructTag(fmt.Sprintf(`gurlf:"K%d"`, i)),
+		}
+		raw = fmt.Appendf(raw, "K%d: v%d\n", n-1-i, n-1-i)
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(fmt.Appendf(nil, "[wide]\n%s[\\wide]", raw))
+	if err != nil {
+		panic(err)
+	}
+	return reflect.StructOf(fields), scanner.Detach(ds)[0]
+}
+
+func TestUnmarshalWide(t *testing.T) {
+	for _, n := range []int{1, 10, 500} {
+		typ, d := wide(n)
+		v := reflect.New(typ)
+		if err := Unmarshal(d, v.Interface()); err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", n, err)
+		}
+		for i := range n {
+			if got, want := v.Elem().Field(i).String(), fmt.Sprintf("v%d", i); got != want {
+				t.Errorf("[%d]: field %d: expected %q, got %q", n, i, want, got)
+			}
+		}
+	}
+}
+
+func BenchmarkUnmarshalWide(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			typ, d := wide(n)
+			v := reflect.New(typ).Interface()
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := Unmarshal(d, v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func TestMarshal(t *testing.T) {
 	type testData struct {
 		ID   int    `gurlf:"ID"`
@@ -256,3 +315,582 @@ func TestInlineMarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestUnmarshalAll(t *testing.T) {
+	raw := []byte(`
+[login]
+ID: 1
+URL: /login
+[\login]
+[health]
+ID: 2
+URL: /health
+[\health]
+[login]
+ID: 3
+URL: /v2/login
+[\login]
+`)
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type request struct {
+		Name string `gurlf:"config_name"`
+		ID   int    `gurlf:"ID"`
+		URL  string `gurlf:"URL"`
+	}
+
+	var list []request
+	if err := UnmarshalAll(ds, &list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ptrs []*request
+	if err := UnmarshalAll(ds, &ptrs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var byName map[string]request
+	if err := UnmarshalAll(ds, &byName); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc struct {
+		Health *request   `gurlf:"health"`
+		Login  []request  `gurlf:"login"`
+		Other  request    `gurlf:"other"`
+		Extra  []*request `gurlf:"extra"`
+	}
+	if err := UnmarshalAll(ds, &doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		expected request
+		actual   request
+	}{
+		{request{"login", 1, "/login"}, list[0]},
+		{request{"health", 2, "/health"}, list[1]},
+		{request{"login", 3, "/v2/login"}, list[2]},
+		{request{"health", 2, "/health"}, *ptrs[1]},
+		{request{"health", 2, "/health"}, byName["health"]},
+		{request{"login", 3, "/v2/login"}, byName["login"]},
+		{request{"health", 2, "/health"}, *doc.Health},
+		{request{"login", 1, "/login"}, doc.Login[0]},
+		{request{"login", 3, "/v2/login"}, doc.Login[1]},
+		{request{}, doc.Other},
+	}
+	for i, tt := range tests {
+		if tt.actual != tt.expected {
+			t.Errorf("[%d]: expected %+v, got %+v", i, tt.expected, tt.actual)
+		}
+	}
+
+	if len(list) != 3 || len(ptrs) != 3 || len(byName) != 2 || len(doc.Login) != 2 || doc.Extra != nil {
+		t.Errorf("len mismatch: got %d, %d, %d, %d, %v",
+			len(list), len(ptrs), len(byName), len(doc.Login), doc.Extra)
+	}
+}
+
+func TestMarshalSections(t *testing.T) {
+	type request struct {
+		Name string `gurlf:"config_name"`
+		ID   int    `gurlf:"ID"`
+		URL  string `gurlf:"URL"`
+	}
+	list := []request{
+		{"login", 1, "/login"},
+		{"health", 2, "/health"},
+	}
+	type doc struct {
+		Health *request  `gurlf:"health"`
+		Login  []request `gurlf:"login"`
+	}
+
+	tests := []struct {
+		input any
+		want  string
+	}{
+		{
+			input: list,
+			want:  "[login]\nID:1\nURL:/login\n[\\login]\n\n[health]\nID:2\nURL:/health\n[\\health]\n\n",
+		},
+		{
+			input: map[string]request{"b": list[1], "a": list[0]},
+			want:  "[a]\nID:1\nURL:/login\n[\\a]\n\n[b]\nID:2\nURL:/health\n[\\b]\n\n",
+		},
+		{
+			input: doc{Health: &list[1], Login: list[:1]},
+			want:  "[health]\nID:2\nURL:/health\n[\\health]\n\n[login]\nID:1\nURL:/login\n[\\login]\n\n",
+		},
+	}
+
+	for i, tt := range tests {
+		b, err := Marshal(tt.input)
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("[%d]:\n Got: %q\nWant: %q", i, b, tt.want)
+		}
+	}
+
+	b, err := Marshal(&list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []request
+	if err := UnmarshalAll(ds, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, list) {
+		t.Errorf("round trip mismatch:\n Got: %v\nWant: %v", got, list)
+	}
+}
+
+func TestNestedSections(t *testing.T) {
+	type header struct {
+		Key   string `gurlf:"KEY"`
+		Value string `gurlf:"VALUE"`
+	}
+	type auth struct {
+		Token string `gurlf:"TOKEN"`
+	}
+	type request struct {
+		Name    string   `gurlf:"config_name"`
+		URL     string   `gurlf:"URL"`
+		Auth    *auth    `gurlf:"auth"`
+		Headers []header `gurlf:"header"`
+	}
+
+	want := request{
+		Name: "login",
+		URL:  "/login",
+		Auth: &auth{Token: "secret"},
+		Headers: []header{
+			{"Accept", "*/*"},
+			{"X-Id", "1"},
+		},
+	}
+	const raw = "[login]\nURL:/login\n" +
+		"[auth]\nTOKEN:secret\n[\\auth]\n" +
+		"[header]\nKEY:Accept\nVALUE:*/*\n[\\header]\n" +
+		"[header]\nKEY:X-Id\nVALUE:1\n[\\header]\n" +
+		"[\\login]\n\n"
+
+	b, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != raw {
+		t.Errorf("\n Got: %q\nWant: %q", b, raw)
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := request{Headers: []header{{"stale", "stale"}}}
+	if err := Unmarshal(ds[0], &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\n Got: %+v\nWant: %+v", got, want)
+	}
+}
+
+func TestUnmarshalOptions(t *testing.T) {
+	type cfg struct {
+		ID      int    `gurlf:"ID"`
+		Headers string `gurlf:"HEADERS"`
+	}
+	type doc struct {
+		Req cfg `gurlf:"req"`
+	}
+
+	tests := []struct {
+		input string
+		opts  UnmarshalOptions
+		err   error
+		key   string
+		line  int
+	}{
+		{"[req]\nID: 1\nHEDAERS: x\n[\\req]", UnmarshalOptions{}, nil, "", 0},
+		{"[req]\nID: 1\nHEDAERS: x\n[\\req]", UnmarshalOptions{DisallowUnknownKeys: true}, ErrUnknownKey, "HEDAERS", 3},
+		{"[req]\nID: 1\n# note\nID: 2\n[\\req]", UnmarshalOptions{}, nil, "", 0},
+		{"[req]\nID: 1\n# note\nID: 2\n[\\req]", UnmarshalOptions{DisallowDuplicateKeys: true}, ErrDuplicateKey, "ID", 4},
+		{"\n[req]\nID: 1\n[\\req]", UnmarshalOptions{DisallowUnknownKeys: true, DisallowDuplicateKeys: true}, nil, "", 0},
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	for i, tt := range tests {
+		ds, err := s.Scan([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+
+		var c cfg
+		err = tt.opts.Unmarshal(ds[0], &c)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("[%d]: expected %v, got %v", i, tt.err, err)
+		}
+		if tt.err == nil {
+			continue
+		}
+
+		var ke *KeyError
+		if !errors.As(err, &ke) {
+			t.Fatalf("[%d]: expected *KeyError, got %T", i, err)
+		}
+		if ke.Key != tt.key || ke.Section != "req" || ke.Line != tt.line {
+			t.Errorf("[%d]: expected %q in \"req\" at line %d, got %v", i, tt.key, tt.line, ke)
+		}
+
+		nested := "[doc]\n" + tt.input + "\n[\\doc]"
+		if ds, err = s.Scan([]byte(nested)); err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		if err := tt.opts.Unmarshal(ds[0], &doc{}); !errors.As(err, &ke) || ke.Line != tt.line+1 {
+			t.Errorf("[%d]: nested: expected line %d, got %v", i, tt.line+1, err)
+		}
+	}
+}
+
+func TestTagOptions(t *testing.T) {
+	type cfg struct {
+		ID      int           `gurlf:"ID,required"`
+		Timeout time.Duration `gurlf:"TIMEOUT,default=30s"`
+		Tags    string        `gurlf:"TAGS,omitempty,default=a,b"`
+		Retries *int          `gurlf:"RETRIES,default=3"`
+	}
+	three := 3
+
+	tests := []struct {
+		input string
+		want  cfg
+		err   error
+	}{
+		{"[c]\nID: 1\n[\\c]", cfg{1, 30 * time.Second, "a,b", &three}, nil},
+		{"[c]\nID: 1\nTIMEOUT: 1m\nTAGS: x\nRETRIES: 3\n[\\c]", cfg{1, time.Minute, "x", &three}, nil},
+		{"[c]\nTIMEOUT: 1m\n[\\c]", cfg{}, ErrMissingKey},
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	for i, tt := range tests {
+		ds, err := s.Scan([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+
+		var got cfg
+		err = Unmarshal(ds[0], &got)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("[%d]: expected %v, got %v", i, tt.err, err)
+		}
+		if tt.err != nil {
+			var ke *KeyError
+			if !errors.As(err, &ke) || ke.Key != "ID" || ke.Section != "c" || ke.Line != 1 {
+				t.Errorf("[%d]: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d]:\n Got: %+v\nWant: %+v", i, got, tt.want)
+		}
+	}
+}
+
+func TestListFields(t *testing.T) {
+	type cfg struct {
+		Headers []string  `gurlf:"HEADER"`
+		Ports   []int     `gurlf:"PORT"`
+		Levels  []level   `gurlf:"LEVEL"`
+		Tags    []*string `gurlf:"TAG,default=none"`
+		Body    []byte    `gurlf:"BODY"`
+	}
+	none := "none"
+	want := cfg{
+		Headers: []string{"Accept: */*", "X-Id: 1", "Multi\nline"},
+		Ports:   []int{80, 443},
+		Levels:  []level{"info", "debug"},
+		Tags:    []*string{&none},
+		Body:    []byte("raw"),
+	}
+	const raw = "[c]\n" +
+		"HEADER:Accept: */*\nHEADER:X-Id: 1\nHEADER:``\nMulti\nline\n``\n" +
+		"PORT:80\nPORT:443\n" +
+		"LEVEL:INFO\nLEVEL:DEBUG\n" +
+		"TAG:none\n" +
+		"BODY:raw\n" +
+		"[\\c]\n\n"
+
+	b, err := Marshal(struct {
+		Name string `gurlf:"config_name"`
+		cfg
+	}{"c", want})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != raw {
+		t.Errorf("\n Got: %q\nWant: %q", b, raw)
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan([]byte(strings.Replace(raw, "TAG:none\n", "", 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := cfg{Headers: []string{"stale"}, Ports: make([]int, 0, 1)}
+	opts := UnmarshalOptions{DisallowDuplicateKeys: true}
+	if err := opts.Unmarshal(ds[0], &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\n Got: %+v\nWant: %+v", got, want)
+	}
+}
+
+func TestMapFields(t *testing.T) {
+	type request struct {
+		Name    string              `gurlf:"config_name"`
+		URL     string              `gurlf:"URL"`
+		Headers map[string]string   `gurlf:"headers"`
+		Query   map[string][]string `gurlf:"query"`
+		Extra   map[string]level    `gurlf:",remain"`
+	}
+	want := request{
+		Name:    "req",
+		URL:     "/login",
+		Headers: map[string]string{"X-Id": "1", "Accept": "*/*"},
+		Query:   map[string][]string{"tag": {"a", "b"}, "id": {"1"}},
+		Extra:   map[string]level{"MODE": "fast", "LEVEL": "debug"},
+	}
+	const raw = "[req]\nURL:/login\nLEVEL:DEBUG\nMODE:FAST\n" +
+		"[headers]\nAccept:*/*\nX-Id:1\n[\\headers]\n" +
+		"[query]\nid:1\ntag:a\ntag:b\n[\\query]\n" +
+		"[\\req]\n\n"
+
+	b, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != raw {
+		t.Errorf("\n Got: %q\nWant: %q", b, raw)
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got request
+	opts := UnmarshalOptions{DisallowUnknownKeys: true, DisallowDuplicateKeys: true}
+	if err := opts.Unmarshal(ds[0], &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\n Got: %+v\nWant: %+v", got, want)
+	}
+
+	var headers map[string]string
+	if err := Unmarshal(ds[0].Children[0], &headers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(headers, want.Headers) {
+		t.Errorf("\n Got: %v\nWant: %v", headers, want.Headers)
+	}
+	if b, err := Marshal(headers); err != nil || string(b) != "Accept:*/*\nX-Id:1\n" {
+		t.Errorf("unexpected map encoding %q: %v", b, err)
+	}
+
+	err = opts.Unmarshal(ds[0].Children[1], &map[string]string{})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected %v, got %v", ErrDuplicateKey, err)
+	}
+}
+
+type roundTrip struct {
+	Name  string `gurlf:"config_name"`
+	ID    int    `gurlf:"ID"`
+	User  string `gurlf:"User"`
+	Body  string `gurlf:"Body"`
+	Raw   []byte `gurlf:"Raw"`
+	Extra string `gurlf:"Extra"`
+}
+
+var roundTripParts = []string{
+	"x", "value", " ", "\t", "\n", "\r", "\r\n", "`", "``", "```", ":", "#",
+	"[rt]", "[\\rt]", "[\\", "\n`\n", "\n``\n", "{ \"key\": `v` }",
+}
+
+func randomText(r *rand.Rand, size int) string {
+	var sb strings.Builder
+	for range r.Intn(size + 1) {
+		sb.WriteString(roundTripParts[r.Intn(len(roundTripParts))])
+	}
+	return sb.String()
+}
+
+func (roundTrip) Generate(r *rand.Rand, size int) reflect.Value {
+	v := roundTrip{
+		Name:  "rt",
+		ID:    r.Int() - r.Int(),
+		User:  randomText(r, size),
+		Body:  randomText(r, size),
+		Extra: randomText(r, size),
+	}
+	if raw := randomText(r, size); raw != "" {
+		v.Raw = []byte(raw)
+	}
+	return reflect.ValueOf(v)
+}
+
+func TestMarshalInvalidNames(t *testing.T) {
+	type section struct {
+		Name string `gurlf:"config_name"`
+		ID   int    `gurlf:"ID"`
+	}
+
+	tests := []struct {
+		v   any
+		err error
+	}{
+		{map[string]string{"A:B": "v"}, ErrInvalidKey},
+		{map[string]string{"a\nb": "v"}, ErrInvalidKey},
+		{map[string]string{"#note": "v"}, ErrInvalidKey},
+		{map[string]string{"[x": "v"}, ErrInvalidKey},
+		{map[string]string{" A": "v"}, ErrInvalidKey},
+		{map[string]string{"": "v"}, ErrInvalidKey},
+		{map[string]map[string]string{"a]b": {"K": "v"}}, ErrInvalidName},
+		{map[string]map[string]string{"\\a": {"K": "v"}}, ErrInvalidName},
+		{map[string]map[string]string{"ok": {"K:": "v"}}, ErrInvalidKey},
+		{[]section{{Name: "a\nb", ID: 1}}, ErrInvalidName},
+		{map[string]section{"x]": {ID: 1}}, ErrInvalidName},
+		{map[string]string{"A.B-c d": "v"}, nil},
+	}
+
+	for i, tt := range tests {
+		if _, err := Marshal(tt.v); !errors.Is(err, tt.err) {
+			t.Errorf("[%d]: expected %v, got %v", i, tt.err, err)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+
+	check := func(in roundTrip) bool {
+		b, err := Marshal(in)
+		if err != nil {
+			t.Logf("marshal: %v", err)
+			return false
+		}
+		ds, err := s.Scan(b)
+		if err != nil || len(ds) != 1 {
+			t.Logf("scan %q: %d sections, %v", b, len(ds), err)
+			return false
+		}
+
+		var out roundTrip
+		if err := Unmarshal(ds[0], &out); err != nil {
+			t.Logf("unmarshal %q: %v", b, err)
+			return false
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Logf("mismatch for %q:\n Got: %q\nWant: %q", b, out, in)
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(check, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScalarRoundTrip(t *testing.T) {
+	type scalars struct {
+		Name string     `gurlf:"config_name"`
+		B    bool       `gurlf:"B"`
+		I    int        `gurlf:"I"`
+		I8   int8       `gurlf:"I8"`
+		I16  int16      `gurlf:"I16"`
+		I32  int32      `gurlf:"I32"`
+		I64  int64      `gurlf:"I64"`
+		U    uint       `gurlf:"U"`
+		U8   uint8      `gurlf:"U8"`
+		U16  uint16     `gurlf:"U16"`
+		U32  uint32     `gurlf:"U32"`
+		U64  uint64     `gurlf:"U64"`
+		UP   uintptr    `gurlf:"UP"`
+		F32  float32    `gurlf:"F32"`
+		F64  float64    `gurlf:"F64"`
+		C64  complex64  `gurlf:"C64"`
+		C128 complex128 `gurlf:"C128"`
+	}
+	tests := []scalars{
+		{Name: "min", B: false, I: -1 << 63, I8: -128, I16: -32768, I32: -1 << 31, I64: -1 << 63,
+			F32: -3.4028235e38, F64: -1.7976931348623157e308, C64: complex(-1.5, -2), C128: complex(-1e-300, 3)},
+		{Name: "max", B: true, I: 1<<63 - 1, I8: 127, I16: 32767, I32: 1<<31 - 1, I64: 1<<63 - 1,
+			U: 1<<64 - 1, U8: 255, U16: 65535, U32: 1<<32 - 1, U64: 1<<64 - 1, UP: 1<<64 - 1,
+			F32: 1.1754944e-38, F64: 4.9406564584124654e-324, C64: complex(1, 0.25), C128: complex(0.1, 1e300)},
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	for i, tt := range tests {
+		b, err := Marshal(tt)
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		ds, err := s.Scan(b)
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+
+		var got scalars
+		if err := Unmarshal(ds[0], &got); err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		if got != tt {
+			t.Errorf("[%d]:\n Got: %+v\nWant: %+v", i, got, tt)
+		}
+	}
+}
+
+func TestScalarOverflow(t *testing.T) {
+	tests := []struct {
+		input string
+		dst   any
+	}{
+		{"128", new(int8)},
+		{"-32769", new(int16)},
+		{"4294967296", new(uint32)},
+		{"-1", new(uint)},
+		{"256", new(uint8)},
+		{"1e39", new(float32)},
+		{"yes", new(bool)},
+	}
+
+	for i, tt := range tests {
+		if err := setValue(reflect.ValueOf(tt.dst).Elem(), []byte(tt.input)); err == nil {
+			t.Errorf("[%d]: expected error for %q into %T", i, tt.input, tt.dst)
+		}
+	}
+}
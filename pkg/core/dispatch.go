@@ -0,0 +1,60 @@
+package core
+
+import "bytes"
+
+// dispatch maps a key to the first unmFields entry tagged with it using
+// open addressing. Fields sharing a tag are chained through field.next.
+type dispatch struct {
+	slots []int32
+	mask  uint32
+}
+
+func newDispatch(fields []field) dispatch {
+	size := 8
+	for size < 2*len(fields) {
+		size <<= 1
+	}
+	d := dispatch{
+		slots: make([]int32, size),
+		mask:  uint32(size - 1),
+	}
+
+	last := make(map[string]int, len(fields))
+	for i := range fields {
+		fields[i].next = -1
+		if prev, ok := last[string(fields[i].tag)]; ok {
+			fields[prev].next = i
+			last[string(fields[i].tag)] = i
+			continue
+		}
+		last[string(fields[i].tag)] = i
+
+		h := hashKey(fields[i].tag) & d.mask
+		for d.slots[h] != 0 {
+			h = (h + 1) & d.mask
+		}
+		d.slots[h] = int32(i + 1)
+	}
+
+	return d
+}
+
+// lookup returns the index of the first field tagged with key, or -1.
+func (d dispatch) lookup(fields []field, key []byte) int {
+	for h := hashKey(key) & d.mask; d.slots[h] != 0; h = (h + 1) & d.mask {
+		if i := int(d.slots[h] - 1); bytes.Equal(fields[i].tag, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hashKey is 32-bit FNV-1a.
+func hashKey(key []byte) uint32 {
+	h := uint32(2166136261)
+	for _, c := range key {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	return h
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown key")
+	ErrDuplicateKey = errors.New("duplicate key")
+	ErrMissingKey   = errors.New("missing key")
+	ErrInvalidKey   = errors.New("invalid key")
+	ErrInvalidName  = errors.New("invalid section name")
+
+	ErrUnresolvedRef  = errors.New("unresolved reference")
+	ErrRefCycle       = errors.New("reference cycle")
+	ErrBadRef         = errors.New("unterminated reference")
+	ErrExpandTooLarge = errors.New("expanded value too large")
+)
+
+// KeyError reports a key rejected by UnmarshalOptions or a required key
+// missing from a section.
+// Line is the 1-based input line of the key, or of the section header
+// when the key is missing.
+type KeyError struct {
+	Section string
+	Key     string
+	Line    int
+	Err     error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%d: %v %q in section %q", e.Line, e.Err, e.Key, e.Section)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+// Resolver supplies the values of ${...} references that are not keys of
+// the document being decoded. ref is the text between the braces.
+type Resolver interface {
+	Resolve(ref string) (value string, ok bool)
+}
+
+// ResolverFunc adapts a function to Resolver.
+type ResolverFunc func(ref string) (string, bool)
+
+func (f ResolverFunc) Resolve(ref string) (string, bool) { return f(ref) }
+
+// EnvResolver resolves ${env:NAME} from the process environment.
+type EnvResolver struct{}
+
+func (EnvResolver) Resolve(ref string) (string, bool) {
+	name, ok := strings.CutPrefix(ref, "env:")
+	if !ok {
+		return "", false
+	}
+	return os.LookupEnv(name)
+}
+
+// MapResolver resolves references by exact name.
+type MapResolver map[string]string
+
+func (m MapResolver) Resolve(ref string) (string, bool) {
+	v, ok := m[ref]
+	return v, ok
+}
+
+// MultiResolver asks each resolver in turn.
+type MultiResolver []Resolver
+
+func (m MultiResolver) Resolve(ref string) (string, bool) {
+	for _, r := range m {
+		if v, ok := r.Resolve(ref); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// maxExpanded caps the length of an expanded value, so that keys doubling
+// each other's references cannot grow a value exponentially.
+const maxExpanded = 1 << 20
+
+// expander replaces the references of the values of one Unmarshal or
+// UnmarshalAll call. Bare names are keys of sec, "section.KEY" names a
+// key of another top-level section when the whole document is known,
+// and anything else goes to r. Expanded keys are kept in done, so each
+// is expanded once per call.
+type expander struct {
+	r        Resolver
+	sections []scanner.Data
+	stack    []keyRef
+	refs     []string
+	done     map[keyRef][]byte
+}
+
+func newExpander(r Resolver, sections []scanner.Data) *expander {
+	if r == nil {
+		r = EnvResolver{}
+	}
+	return &expander{r: r, sections: sections, done: make(map[keyRef][]byte)}
+}
+
+func (o UnmarshalOptions) expander() *expander {
+	if o.x != nil {
+		return o.x
+	}
+	return newExpander(o.Resolver, nil)
+}
+
+// expandEntry returns the value of ent with its references replaced.
+// Backtick blocks are left alone unless raw is set.
+func (o UnmarshalOptions) expandEntry(d scanner.Data, ent scanner.Entry, raw bool) ([]byte, error) {
+	val := d.RawData[ent.ValStart:ent.ValEnd]
+	if !raw && isBlock(d, ent) || bytes.IndexByte(val, '$') == -1 {
+		return val, nil
+	}
+
+	res, err := o.expander().expand(nil, val, d)
+	if err != nil {
+		return nil, keyError(d, ent, err)
+	}
+	return res, nil
+}
+
+// expandDefault replaces the references of a tag default, which are
+// looked up in the section being decoded.
+func (o UnmarshalOptions) expandDefault(d scanner.Data, def []byte) ([]byte, error) {
+	if bytes.IndexByte(def, '$') == -1 {
+		return def, nil
+	}
+	return o.expander().expand(nil, def, d)
+}
+
+// isBlock reports whether the value of ent was written in backticks.
+func isBlock(d scanner.Data, ent scanner.Entry) bool {
+	if ent.ValStart == 0 {
+		return false
+	}
+	c := d.RawData[ent.ValStart-1]
+	return c == '`' || c == '\n'
+}
+
+// expand appends s to dst with "$${" turned into "${" and every ${ref}
+// replaced by its value, itself expanded in the section it comes from.
+func (x *expander) expand(dst, s []byte, sec scanner.Data) ([]byte, error) {
+	var err error
+	for {
+		i := bytes.Index(s, []byte("${"))
+		if i == -1 {
+			return x.append(dst, s)
+		}
+		if i > 0 && s[i-1] == '$' {
+			if dst, err = x.append(dst, s[:i]); err != nil {
+				return nil, err
+			}
+			dst = append(dst, '{')
+			s = s[i+2:]
+			continue
+		}
+
+		end := bytes.IndexByte(s[i:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("%w %q", ErrBadRef, s[i:])
+		}
+		ref := string(s[i+2 : i+end])
+		if dst, err = x.append(dst, s[:i]); err != nil {
+			return nil, err
+		}
+		s = s[i+end+1:]
+
+		if dst, err = x.resolve(dst, ref, sec); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// append appends s to dst unless that makes the value too long.
+func (x *expander) append(dst, s []byte) ([]byte, error) {
+	if len(dst)+len(s) > maxExpanded {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrExpandTooLarge, maxExpanded)
+	}
+	return append(dst, s...), nil
+}
+
+// resolve appends the value of ref. Keys are expanded in their own
+// section; resolver values are taken as they are.
+func (x *expander) resolve(dst []byte, ref string, sec scanner.Data) ([]byte, error) {
+	val, from, key, ok := x.lookup(ref, sec)
+	if !ok {
+		v, ok := x.r.Resolve(ref)
+		if !ok {
+			return nil, fmt.Errorf("%w ${%s}", ErrUnresolvedRef, ref)
+		}
+		return x.append(dst, []byte(v))
+	}
+	if v, ok := x.done[key]; ok {
+		return x.append(dst, v)
+	}
+
+	if i := slices.Index(x.stack, key); i != -1 {
+		chain := append(slices.Clone(x.refs[i:]), ref)
+		return nil, fmt.Errorf("%w: ${%s}", ErrRefCycle, strings.Join(chain, "} -> ${"))
+	}
+
+	x.stack, x.refs = append(x.stack, key), append(x.refs, ref)
+	start := len(dst)
+	dst, err := x.expand(dst, val, from)
+	x.stack, x.refs = x.stack[:len(x.stack)-1], x.refs[:len(x.refs)-1]
+	if err != nil {
+		return nil, err
+	}
+	x.done[key] = dst[start:len(dst):len(dst)]
+	return dst, nil
+}
+
+// keyRef identifies a key by the section holding it. Sections are told
+// apart by the start of their data, as names repeat and offsets restart
+// in every included file.
+type keyRef struct {
+	sec *byte
+	key string
+}
+
+// lookup finds the raw value of the key ref names and the section it
+// belongs to. Keys resolve to their last occurrence, as Unmarshal would
+// store them.
+func (x *expander) lookup(ref string, sec scanner.Data) ([]byte, scanner.Data, keyRef, bool) {
+	if v, ok := lastValue(sec, ref); ok {
+		return v, sec, keyRef{&sec.RawData[0], ref}, true
+	}
+	// Section names and keys may both hold dots, so try every split.
+	for i := strings.IndexByte(ref, '.'); i != -1; {
+		for _, d := range x.sections {
+			if string(d.Name) == ref[:i] {
+				if v, ok := lastValue(d, ref[i+1:]); ok {
+					return v, d, keyRef{&d.RawData[0], ref[i+1:]}, true
+				}
+			}
+		}
+		j := strings.IndexByte(ref[i+1:], '.')
+		if j == -1 {
+			break
+		}
+		i += j + 1
+	}
+	return nil, sec, keyRef{}, false
+}
+
+func lastValue(d scanner.Data, key string) ([]byte, bool) {
+	for i := len(d.Entries) - 1; i >= 0; i-- {
+		ent := d.Entries[i]
+		if string(d.RawData[ent.KeyStart:ent.KeyEnd]) == key {
+			return d.RawData[ent.ValStart:ent.ValEnd], true
+		}
+	}
+	return nil, false
+}
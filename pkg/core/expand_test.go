@@ -0,0 +1,188 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("GURLF_TOKEN", "s3cret")
+
+	type request struct {
+		Name    string            `gurlf:"config_name"`
+		URL     string            `gurlf:"URL"`
+		Auth    string            `gurlf:"AUTH,default=Bearer ${env:GURLF_TOKEN}"`
+		Body    string            `gurlf:"BODY"`
+		Headers string            `gurlf:"HEADERS,expand"`
+		Rest    map[string]string `gurlf:",remain"`
+	}
+
+	raw := []byte("[common]\nBASE_URL: https://${HOST}\nHOST: example.com\n[\\common]\n" +
+		"[login]\nURL: ${common.BASE_URL}/login\nBODY: `{\"t\": \"${env:GURLF_TOKEN}\"}`\n" +
+		"HEADERS: `\nX-Token: ${env:GURLF_TOKEN}\n`\nPRICE: $${5} and $5\n[\\login]\n")
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var reqs []request
+	if err := (UnmarshalOptions{Expand: true}).UnmarshalAll(ds, &reqs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := reqs[1]
+	if got.URL != "https://example.com/login" {
+		t.Errorf("URL: got %q", got.URL)
+	}
+	if got.Auth != "Bearer s3cret" {
+		t.Errorf("AUTH: got %q", got.Auth)
+	}
+	if got.Body != `{"t": "${env:GURLF_TOKEN}"}` {
+		t.Errorf("BODY: expected the block as written, got %q", got.Body)
+	}
+	if got.Headers != "\nX-Token: s3cret\n" {
+		t.Errorf("HEADERS: got %q", got.Headers)
+	}
+	if got.Rest["PRICE"] != "${5} and $5" {
+		t.Errorf("PRICE: got %q", got.Rest["PRICE"])
+	}
+
+	if err := UnmarshalAll(ds, &reqs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reqs[1].URL != "${common.BASE_URL}/login" {
+		t.Errorf("expected no expansion by default, got %q", reqs[1].URL)
+	}
+}
+
+func TestExpandResolver(t *testing.T) {
+	type cfg struct {
+		URL string `gurlf:"URL"`
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan([]byte("[api]\nURL: ${BASE_URL}/v1?k=${env:GURLF_UNSET_KEY}\n[\\api]"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts := UnmarshalOptions{Expand: true, Resolver: MultiResolver{
+		MapResolver{"BASE_URL": "http://${literal}"},
+		ResolverFunc(func(ref string) (string, bool) { return strings.ToUpper(ref), true }),
+	}}
+	var c cfg
+	if err := opts.Unmarshal(ds[0], &c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.URL != "http://${literal}/v1?k=ENV:GURLF_UNSET_KEY" {
+		t.Errorf("got %q", c.URL)
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	type cfg struct {
+		A string `gurlf:"A"`
+		B string `gurlf:"B"`
+	}
+
+	tests := []struct {
+		input string
+		err   error
+		msg   string
+	}{
+		{"A: ${env:GURLF_UNSET_KEY}", ErrUnresolvedRef, "${env:GURLF_UNSET_KEY}"},
+		{"A: ${nope", ErrBadRef, "${nope"},
+		{"A: ${B}\nB: ${A}", ErrRefCycle, "${B} -> ${A} -> ${B}"},
+		{"A: ${A}", ErrRefCycle, "${A} -> ${A}"},
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	for i, tt := range tests {
+		ds, err := s.Scan([]byte("[req]\n" + tt.input + "\n[\\req]"))
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+
+		err = UnmarshalOptions{Expand: true}.Unmarshal(ds[0], &cfg{})
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("[%d]: expected %v, got %v", i, tt.err, err)
+		}
+		var ke *KeyError
+		if !errors.As(err, &ke) || ke.Key != "A" || ke.Line != 2 {
+			t.Errorf("[%d]: expected key \"A\" at line 2, got %v", i, err)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("[%d]: expected %q in %q", i, tt.msg, err)
+		}
+	}
+}
+
+func TestExpandOnce(t *testing.T) {
+	type cfg struct {
+		A string `gurlf:"A"`
+		B string `gurlf:"B"`
+	}
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan([]byte("[common]\nTOKEN: ${env:TOKEN}\n[\\common]\n" +
+		"[a]\nT: ${common.TOKEN}\nA: ${T}${T}${T}\nB: ${T}-${common.TOKEN}\n[\\a]\n" +
+		"[b]\nA: ${common.TOKEN}\n[\\b]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := 0
+	opts := UnmarshalOptions{Expand: true, Resolver: ResolverFunc(func(ref string) (string, bool) {
+		calls++
+		return "t", true
+	})}
+	var cfgs []cfg
+	if err := opts.UnmarshalAll(ds, &cfgs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfgs[1].A != "ttt" || cfgs[1].B != "t-t" || cfgs[2].A != "t" {
+		t.Errorf("unexpected values: %+v", cfgs)
+	}
+	if calls != 1 {
+		t.Errorf("expected one resolver call, got %d", calls)
+	}
+}
+
+func TestExpandTooLarge(t *testing.T) {
+	// Every key doubles the one before, for 2^40 bytes at K40.
+	var sb strings.Builder
+	sb.WriteString("[req]\nK0: ab\n")
+	for i := 1; i <= 40; i++ {
+		fmt.Fprintf(&sb, "K%d: ${K%d}${K%d}\n", i, i-1, i-1)
+	}
+	sb.WriteString("A: ${K40}\n[\\req]")
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan([]byte(sb.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var c struct {
+		K10 string `gurlf:"K10"`
+		A   string `gurlf:"A"`
+	}
+	err = UnmarshalOptions{Expand: true}.Unmarshal(ds[0], &c)
+	var ke *KeyError
+	if !errors.Is(err, ErrExpandTooLarge) || !errors.As(err, &ke) || ke.Key != "A" {
+		t.Fatalf("expected ErrExpandTooLarge for A, got %v", err)
+	}
+	if len(c.K10) != 2<<10 {
+		t.Errorf("expected K10 of %d bytes, got %d", 2<<10, len(c.K10))
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+// SectionUnmarshaler is implemented by types that decode a whole section
+// themselves, usually with code generated by gurlfgen. Unmarshal prefers
+// it over reflection unless strict options are set.
+type SectionUnmarshaler interface {
+	UnmarshalGurlf(scanner.Data) error
+}
+
+// Generated marks the types gurlfgen writes methods for. Their
+// UnmarshalGurlf does not keep its receiver, so Unmarshal calls it on the
+// target in place; other SectionUnmarshalers decode a copy. It is not
+// meant to be implemented by hand.
+type Generated interface {
+	SectionUnmarshaler
+	GurlfGenerated()
+}
+
+// SectionAppender is implemented by types that encode the body of their
+// section themselves: its keys and nested sections, without the header.
+// Marshal prefers it over reflection for sections with a name.
+type SectionAppender interface {
+	AppendGurlf([]byte) []byte
+}
+
+var (
+	sectionUnmarshalerType = reflect.TypeFor[SectionUnmarshaler]()
+	sectionAppenderType    = reflect.TypeFor[SectionAppender]()
+	generatedType          = reflect.TypeFor[Generated]()
+)
+
+// AppendText appends s the way Marshal writes string values, so that the
+// scanner reads it back unchanged. It is used by generated code.
+func AppendText(dst []byte, s string) []byte {
+	return appendText(dst, s)
+}
+
+// unmarshalGenerated decodes d into the addressable rv through its
+// SectionUnmarshaler. Calling it through rv.Addr().Interface() would make
+// every Unmarshal target escape, generated or not, so the pointer is
+// hidden from escape analysis. Only Generated types get that pointer;
+// others decode a heap copy, which their method may keep.
+func unmarshalGenerated(d scanner.Data, rv reflect.Value, inPlace bool) error {
+	v := reflect.NewAt(rv.Type(), noescape(rv.Addr().UnsafePointer()))
+	if inPlace {
+		return v.Interface().(Generated).UnmarshalGurlf(d)
+	}
+
+	p := reflect.New(rv.Type())
+	p.Elem().Set(v.Elem())
+	if err := p.Interface().(SectionUnmarshaler).UnmarshalGurlf(d); err != nil {
+		return err
+	}
+	v.Elem().Set(p.Elem())
+	return nil
+}
+
+// noescape hides p from escape analysis, like the runtime function of
+// the same name, by passing it through a uintptr.
+func noescape(p unsafe.Pointer) unsafe.Pointer {
+	x := uintptr(p)
+	return *(*unsafe.Pointer)(unsafe.Pointer(&x))
+}
+
+func appendGenerated(dst []byte, rv reflect.Value) []byte {
+	if !rv.CanAddr() {
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p.Elem()
+	}
+	return rv.Addr().Interface().(SectionAppender).AppendGurlf(dst)
+}
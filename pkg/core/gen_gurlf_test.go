@@ -0,0 +1,317 @@
+// Code generated by gurlfgen; DO NOT EDIT.
+
+package core_test
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"github.com/Votline/Gurlf/pkg/core"
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+func (*genRequest) GurlfGenerated() {}
+
+func (x *genRequest) UnmarshalGurlf(d scanner.Data) error {
+	const op = "genRequest.UnmarshalGurlf"
+
+	if len(d.Name) > 0 {
+		x.Name = unsafe.String(unsafe.SliceData(d.Name), len(d.Name))
+	}
+	var has1 bool
+	var has3 bool
+	var has4 bool
+	var has10 bool
+	for _, ent := range d.Entries {
+		val := d.RawData[ent.ValStart:ent.ValEnd]
+		str := unsafe.String(unsafe.SliceData(val), len(val))
+		switch string(d.RawData[ent.KeyStart:ent.KeyEnd]) {
+		case "ID":
+			has1 = true
+			if len(val) > 0 {
+				n, err := strconv.ParseInt(str, 10, 0)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", op, "ID", err)
+				}
+				x.ID = int(n)
+			}
+		case "URL":
+			if len(val) > 0 {
+				x.URL = str
+			}
+		case "TIMEOUT":
+			has3 = true
+			if len(val) > 0 {
+				n, err := time.ParseDuration(str)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", op, "TIMEOUT", err)
+				}
+				x.Timeout = n
+			}
+		case "TAG":
+			if !has4 {
+				x.Tags = x.Tags[:0]
+			}
+			has4 = true
+			{
+				var v string
+				if len(val) > 0 {
+					v = str
+				}
+				x.Tags = append(x.Tags, v)
+			}
+		case "BODY":
+			if len(val) > 0 {
+				x.Body = val
+			}
+		case "RETRIES":
+			if len(val) > 0 {
+				var v uint8
+				n, err := strconv.ParseUint(str, 10, 8)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", op, "RETRIES", err)
+				}
+				v = uint8(n)
+				x.Retries = &v
+			}
+		case "RATIO":
+			if len(val) > 0 {
+				n, err := strconv.ParseFloat(str, 32)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", op, "RATIO", err)
+				}
+				x.Ratio = float32(n)
+			}
+		case "DEBUG":
+			if len(val) > 0 {
+				n, err := strconv.ParseBool(str)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", op, "DEBUG", err)
+				}
+				x.Debug = n
+			}
+		}
+	}
+	if !has1 {
+		return fmt.Errorf("%s: %w", op, &core.KeyError{Section: string(d.Name), Key: "ID", Line: d.Line, Err: core.ErrMissingKey})
+	}
+	if !has3 {
+		n, err := time.ParseDuration("30s")
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", op, "TIMEOUT", err)
+		}
+		x.Timeout = n
+	}
+	for _, ch := range d.Children {
+		switch string(ch.Name) {
+		case "auth":
+			if x.Auth == nil {
+				x.Auth = new(genAuth)
+			}
+			if err := x.Auth.UnmarshalGurlf(ch); err != nil {
+				return fmt.Errorf("%s: section %q: %w", op, ch.Name, err)
+			}
+		case "header":
+			if !has10 {
+				x.Headers = x.Headers[:0]
+				has10 = true
+			}
+			x.Headers = append(x.Headers, genHeader{})
+			if err := x.Headers[len(x.Headers)-1].UnmarshalGurlf(ch); err != nil {
+				return fmt.Errorf("%s: section %q: %w", op, ch.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (x *genRequest) AppendGurlf(dst []byte) []byte {
+	dst = append(dst, "ID:"...)
+	dst = strconv.AppendInt(dst, int64(x.ID), 10)
+	dst = append(dst, '\n')
+	dst = append(dst, "URL:"...)
+	dst = core.AppendText(dst, string(x.URL))
+	dst = append(dst, '\n')
+	dst = append(dst, "TIMEOUT:"...)
+	dst = append(dst, time.Duration(x.Timeout).String()...)
+	dst = append(dst, '\n')
+	for _, v := range x.Tags {
+		dst = append(dst, "TAG:"...)
+		dst = core.AppendText(dst, string(v))
+		dst = append(dst, '\n')
+	}
+	if x.Body != nil {
+		dst = append(dst, "BODY:"...)
+		dst = core.AppendText(dst, unsafe.String(unsafe.SliceData(x.Body), len(x.Body)))
+		dst = append(dst, '\n')
+	}
+	dst = append(dst, "RETRIES:"...)
+	if x.Retries != nil {
+		dst = strconv.AppendUint(dst, uint64((*x.Retries)), 10)
+	}
+	dst = append(dst, '\n')
+	if math.Float32bits(float32(x.Ratio)) != 0 {
+		dst = append(dst, "RATIO:"...)
+		dst = strconv.AppendFloat(dst, float64(x.Ratio), 'f', -1, 32)
+		dst = append(dst, '\n')
+	}
+	dst = append(dst, "DEBUG:"...)
+	dst = strconv.AppendBool(dst, bool(x.Debug))
+	dst = append(dst, '\n')
+	if x.Auth != nil {
+		dst = append(dst, "[auth]\n"...)
+		dst = x.Auth.AppendGurlf(dst)
+		dst = append(dst, "[\\auth]\n"...)
+	}
+	for i := range x.Headers {
+		dst = append(dst, "[header]\n"...)
+		dst = x.Headers[i].AppendGurlf(dst)
+		dst = append(dst, "[\\header]\n"...)
+	}
+
+	return dst
+}
+
+func (*genAuth) GurlfGenerated() {}
+
+func (x *genAuth) UnmarshalGurlf(d scanner.Data) error {
+	const op = "genAuth.UnmarshalGurlf"
+
+	for _, ent := range d.Entries {
+		val := d.RawData[ent.ValStart:ent.ValEnd]
+		str := unsafe.String(unsafe.SliceData(val), len(val))
+		switch string(d.RawData[ent.KeyStart:ent.KeyEnd]) {
+		case "USER":
+			if len(val) > 0 {
+				x.User = str
+			}
+		case "TOKEN":
+			if len(val) > 0 {
+				x.Token = str
+			}
+		}
+	}
+
+	return nil
+}
+
+func (x *genAuth) AppendGurlf(dst []byte) []byte {
+	dst = append(dst, "USER:"...)
+	dst = core.AppendText(dst, string(x.User))
+	dst = append(dst, '\n')
+	dst = append(dst, "TOKEN:"...)
+	dst = core.AppendText(dst, string(x.Token))
+	dst = append(dst, '\n')
+
+	return dst
+}
+
+func (*genHeader) GurlfGenerated() {}
+
+func (x *genHeader) UnmarshalGurlf(d scanner.Data) error {
+	const op = "genHeader.UnmarshalGurlf"
+
+	for _, ent := range d.Entries {
+		val := d.RawData[ent.ValStart:ent.ValEnd]
+		str := unsafe.String(unsafe.SliceData(val), len(val))
+		switch string(d.RawData[ent.KeyStart:ent.KeyEnd]) {
+		case "KEY":
+			if len(val) > 0 {
+				x.Key = str
+			}
+		case "VALUE":
+			if len(val) > 0 {
+				x.Value = str
+			}
+		}
+	}
+
+	return nil
+}
+
+func (x *genHeader) AppendGurlf(dst []byte) []byte {
+	dst = append(dst, "KEY:"...)
+	dst = core.AppendText(dst, string(x.Key))
+	dst = append(dst, '\n')
+	dst = append(dst, "VALUE:"...)
+	dst = core.AppendText(dst, string(x.Value))
+	dst = append(dst, '\n')
+
+	return dst
+}
+
+func (*genShared) GurlfGenerated() {}
+
+func (x *genShared) UnmarshalGurlf(d scanner.Data) error {
+	const op = "genShared.UnmarshalGurlf"
+
+	var has2 bool
+	for _, ent := range d.Entries {
+		val := d.RawData[ent.ValStart:ent.ValEnd]
+		str := unsafe.String(unsafe.SliceData(val), len(val))
+		switch string(d.RawData[ent.KeyStart:ent.KeyEnd]) {
+		case "N":
+			{
+				if len(val) > 0 {
+					n, err := strconv.ParseInt(str, 10, 0)
+					if err != nil {
+						return fmt.Errorf("%s: %s: %w", op, "N", err)
+					}
+					x.A = int(n)
+				}
+			}
+			{
+				if len(val) > 0 {
+					var v int64
+					n, err := strconv.ParseInt(str, 10, 64)
+					if err != nil {
+						return fmt.Errorf("%s: %s: %w", op, "N", err)
+					}
+					v = n
+					x.B = &v
+				}
+			}
+			{
+				if !has2 {
+					x.C = x.C[:0]
+				}
+				has2 = true
+				{
+					var v int
+					if len(val) > 0 {
+						n, err := strconv.ParseInt(str, 10, 0)
+						if err != nil {
+							return fmt.Errorf("%s: %s: %w", op, "N", err)
+						}
+						v = int(n)
+					}
+					x.C = append(x.C, v)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (x *genShared) AppendGurlf(dst []byte) []byte {
+	dst = append(dst, "N:"...)
+	dst = strconv.AppendInt(dst, int64(x.A), 10)
+	dst = append(dst, '\n')
+	dst = append(dst, "N:"...)
+	if x.B != nil {
+		dst = strconv.AppendInt(dst, int64((*x.B)), 10)
+	}
+	dst = append(dst, '\n')
+	for _, v := range x.C {
+		dst = append(dst, "N:"...)
+		dst = strconv.AppendInt(dst, int64(v), 10)
+		dst = append(dst, '\n')
+	}
+
+	return dst
+}
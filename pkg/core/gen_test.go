@@ -0,0 +1,248 @@
+package core_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Votline/Gurlf/pkg/core"
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+//go:generate go run ../../cmd/gurlfgen -type genRequest,genAuth,genHeader,genShared -output gen_gurlf_test.go
+
+type genRequest struct {
+	Name    string        `gurlf:"config_name"`
+	ID      int           `gurlf:"ID,required"`
+	URL     string        `gurlf:"URL"`
+	Timeout time.Duration `gurlf:"TIMEOUT,default=30s"`
+	Tags    []string      `gurlf:"TAG"`
+	Body    []byte        `gurlf:"BODY,omitempty"`
+	Retries *uint8        `gurlf:"RETRIES"`
+	Ratio   float32       `gurlf:"RATIO,omitempty"`
+	Debug   bool          `gurlf:"DEBUG"`
+	Auth    *genAuth      `gurlf:"auth"`
+	Headers []genHeader   `gurlf:"header"`
+}
+
+type genAuth struct {
+	User  string `gurlf:"USER"`
+	Token string `gurlf:"TOKEN"`
+}
+
+type genHeader struct {
+	Key   string `gurlf:"KEY"`
+	Value string `gurlf:"VALUE"`
+}
+
+// genShared has several fields decoding the same key.
+type genShared struct {
+	A int    `gurlf:"N"`
+	B *int64 `gurlf:"N"`
+	C []int  `gurlf:"N"`
+}
+
+// plainRequest mirrors genRequest without generated methods.
+type plainRequest struct {
+	Name    string        `gurlf:"config_name"`
+	ID      int           `gurlf:"ID,required"`
+	URL     string        `gurlf:"URL"`
+	Timeout time.Duration `gurlf:"TIMEOUT,default=30s"`
+	Tags    []string      `gurlf:"TAG"`
+	Body    []byte        `gurlf:"BODY,omitempty"`
+	Retries *uint8        `gurlf:"RETRIES"`
+	Ratio   float32       `gurlf:"RATIO,omitempty"`
+	Debug   bool          `gurlf:"DEBUG"`
+	Auth    *plainAuth    `gurlf:"auth"`
+	Headers []plainHeader `gurlf:"header"`
+}
+
+type (
+	plainAuth   genAuth
+	plainHeader genHeader
+)
+
+const genInput = "[login]\nID: 7\nURL: /login\nTAG: a\nTAG: b\nBODY: `\n{ \"a\": 1 }\n`\n" +
+	"RETRIES: 3\nRATIO: 0.5\nDEBUG: true\n" +
+	"[auth]\nUSER: dev\nTOKEN: secret\n[\\auth]\n" +
+	"[header]\nKEY: Accept\nVALUE: */*\n[\\header]\n" +
+	"[header]\nKEY: X-Id\nVALUE: 1\n[\\header]\n" +
+	"[\\login]\n"
+
+func scanOne(t testing.TB, s string) scanner.Data {
+	ds, err := gurlfScan([]byte(s))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return ds[0]
+}
+
+func gurlfScan(b []byte) ([]scanner.Data, error) {
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+	ds, err := s.Scan(b)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.Detach(ds), nil
+}
+
+func TestGeneratedSharedKey(t *testing.T) {
+	d := scanOne(t, "[s]\nN: 5\nN: 7\n[\\s]\n")
+
+	var got genShared
+	if err := got.UnmarshalGurlf(d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type plainShared genShared
+	var want plainShared
+	if err := core.Unmarshal(d, &want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(plainShared(got), want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+// keeper is a hand-written SectionUnmarshaler that keeps its receiver.
+type keeper struct {
+	ID   string
+	Keep string
+}
+
+var kept *keeper
+
+func (k *keeper) UnmarshalGurlf(d scanner.Data) error {
+	kept = k
+	ent := d.Entries[0]
+	k.ID = string(d.RawData[ent.ValStart:ent.ValEnd])
+	return nil
+}
+
+func TestHandWrittenUnmarshaler(t *testing.T) {
+	d := scanOne(t, "[k]\nID: 7\n[\\k]\n")
+
+	k := keeper{Keep: "set"}
+	if err := core.Unmarshal(d, &k); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k != (keeper{ID: "7", Keep: "set"}) {
+		t.Errorf("unexpected result %+v", k)
+	}
+	if kept == &k {
+		t.Error("expected the method to get a copy, not the target")
+	}
+	if _, ok := any(&genRequest{}).(core.Generated); !ok {
+		t.Error("expected generated types to implement core.Generated")
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	d := scanOne(t, genInput)
+
+	var gen genRequest
+	if err := core.Unmarshal(d, &gen); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var plain plainRequest
+	if err := core.Unmarshal(d, &plain); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	genOut, err := core.Marshal(gen)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plainOut, err := core.Marshal(plain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(genOut) != string(plainOut) {
+		t.Errorf("generated output differs:\n Got: %q\nWant: %q", genOut, plainOut)
+	}
+
+	var back genRequest
+	if err := back.UnmarshalGurlf(scanOne(t, string(genOut))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(back, gen) {
+		t.Errorf("round trip mismatch:\n Got: %+v\nWant: %+v", back, gen)
+	}
+	if gen.Timeout != 30*time.Second || len(gen.Headers) != 2 || gen.Auth.Token != "secret" {
+		t.Errorf("unexpected value: %+v", gen)
+	}
+
+	err = core.Unmarshal(scanOne(t, "[login]\nURL: /\n[\\login]\n"), &gen)
+	var ke *core.KeyError
+	if !errors.As(err, &ke) || ke.Key != "ID" {
+		t.Errorf("expected missing ID, got %v", err)
+	}
+}
+
+func BenchmarkUnmarshalGenerated(b *testing.B) {
+	d := scanOne(b, genInput)
+	b.Run("generated", func(b *testing.B) {
+		var v genRequest
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := v.UnmarshalGurlf(d); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("core", func(b *testing.B) {
+		var v genRequest
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := core.Unmarshal(d, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflect", func(b *testing.B) {
+		var v plainRequest
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := core.Unmarshal(d, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMarshalGenerated(b *testing.B) {
+	d := scanOne(b, genInput)
+	var gen genRequest
+	var plain plainRequest
+	if err := core.Unmarshal(d, &gen); err != nil {
+		b.Fatal(err)
+	}
+	if err := core.Unmarshal(d, &plain); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf []byte
+		for b.Loop() {
+			buf = gen.AppendGurlf(buf[:0])
+		}
+	})
+	b.Run("core", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := core.Marshal(gen); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := core.Marshal(plain); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
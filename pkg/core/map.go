@@ -0,0 +1,143 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+// unmarshalMap stores every entry of d in the string-keyed map rv.
+func (o UnmarshalOptions) unmarshalMap(d scanner.Data, rv reflect.Value) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("invalid value: need string map key, but got %q", rv.Type().Key().Kind())
+	}
+
+	et := rv.Type().Elem()
+	list := isList(et)
+	if list {
+		et = et.Elem()
+	}
+	c := unmarshalCodec(et)
+
+	for i, ent := range d.Entries {
+		key := d.RawData[ent.KeyStart:ent.KeyEnd]
+		if len(key) == 0 {
+			continue
+		}
+		if !list && o.DisallowDuplicateKeys && repeated(d, i) {
+			return keyError(d, ent, ErrDuplicateKey)
+		}
+
+		val := d.RawData[ent.ValStart:ent.ValEnd]
+		if o.Expand {
+			var err error
+			if val, err = o.expandEntry(d, ent, false); err != nil {
+				return err
+			}
+		}
+		if err := setMapEntry(rv, key, val, c, list, !repeated(d, i)); err != nil {
+			return fmt.Errorf("set value %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// setMapEntry stores val under key in m. List values are appended to,
+// unless first is set, which starts the list over.
+func setMapEntry(m reflect.Value, key, val []byte, c codec, list, first bool) error {
+	if m.IsNil() {
+		assign(m, reflect.MakeMap(m.Type()))
+	}
+
+	k := reflect.ValueOf(string(key)).Convert(m.Type().Key())
+	elem := reflect.New(m.Type().Elem()).Elem()
+	target := elem
+	if list {
+		if prev := m.MapIndex(k); !first && prev.IsValid() {
+			elem.Set(prev)
+		}
+		target = grow(elem)
+	}
+
+	if err := setField(target, val, c); err != nil {
+		return err
+	}
+	m.SetMapIndex(k, elem)
+
+	return nil
+}
+
+// appendMapEntries writes one line per value of the string-keyed map v,
+// sorted by key.
+func appendMapEntries(dst []byte, v reflect.Value) ([]byte, error) {
+	et := v.Type().Elem()
+	list := isList(et)
+	if list {
+		et = et.Elem()
+	}
+	c := marshalCodec(et)
+
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	var err error
+	for _, k := range keys {
+		if !validKey(k.String()) {
+			return dst, fmt.Errorf("%w %q", ErrInvalidKey, k.String())
+		}
+		f := marshalField{
+			precomputedTag: append([]byte(k.String()), ':'),
+			codec:          c,
+		}
+
+		ev := v.MapIndex(k)
+		if !list {
+			if dst, err = appendField(dst, f, ev); err != nil {
+				return dst, err
+			}
+			continue
+		}
+		for i := range ev.Len() {
+			if dst, err = appendField(dst, f, ev.Index(i)); err != nil {
+				return dst, err
+			}
+		}
+	}
+
+	return dst, nil
+}
+
+// appendMapSection writes the map v as a section named name, or as bare
+// entries when name is empty.
+func appendMapSection(dst, name []byte, v reflect.Value, top bool) ([]byte, error) {
+	if len(name) == 0 {
+		return appendMapEntries(dst, v)
+	}
+	if !validName(name) {
+		return dst, fmt.Errorf("%w %q", ErrInvalidName, name)
+	}
+
+	dst = append(dst, '[')
+	dst = append(dst, name...)
+	dst = append(dst, ']', '\n')
+
+	var err error
+	if dst, err = appendMapEntries(dst, v); err != nil {
+		return dst, err
+	}
+
+	dst = append(dst, '[', '\\')
+	dst = append(dst, name...)
+	dst = append(dst, ']', '\n')
+	if top {
+		dst = append(dst, '\n')
+	}
+
+	return dst, nil
+}
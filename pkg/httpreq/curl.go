@@ -0,0 +1,336 @@
+package httpreq
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var ErrUnsupportedCurl = errors.New("unsupported curl option")
+
+// curlArgs lists curl options that take an argument but do not change
+// the request itself, so that their argument is not taken for the URL.
+var curlArgs = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true,
+	"--connect-timeout": true, "-x": true, "--proxy": true, "--retry": true,
+	"-w": true, "--write-out": true, "--cacert": true, "-E": true, "--cert": true,
+	"--key": true, "--resolve": true, "-c": true, "--cookie-jar": true,
+	"--max-redirs": true, "--limit-rate": true, "-r": true, "--range": true,
+}
+
+// ParseCurl reads a curl command line as pasted from a shell or a
+// browser's "copy as cURL". Quotes, backslash escapes and line
+// continuations are handled the way a POSIX shell would.
+func ParseCurl(cmd string) (Request, error) {
+	const op = "httpreq.ParseCurl"
+
+	args, err := shellSplit(cmd)
+	if err != nil {
+		return Request{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	var r Request
+	var data []string
+	get, head := false, false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, val, inline := arg, "", false
+		if n, v, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(arg, "--") {
+			name, val, inline = n, v, true
+		}
+		// Short options may carry their value glued on, as in -XPOST.
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && strings.ContainsRune("XHdbAeu", rune(arg[1])) {
+			name, val, inline = arg[:2], arg[2:], true
+		}
+
+		next := func() (string, error) {
+			if inline {
+				return val, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s: %s needs an argument", op, name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch name {
+		case "-X", "--request":
+			if r.Method, err = next(); err != nil {
+				return Request{}, err
+			}
+		case "-H", "--header":
+			h, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			if k, v, ok := strings.Cut(h, ":"); ok && strings.EqualFold(strings.TrimSpace(k), "Cookie") {
+				r.Cookies = appendCookies(r.Cookies, strings.TrimSpace(v))
+				continue
+			}
+			r.Headers = appendLine(r.Headers, h)
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			d, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			if strings.HasPrefix(d, "@") && name != "--data-raw" {
+				return Request{}, fmt.Errorf("%s: %s %s: %w", op, name, d, ErrUnsupportedCurl)
+			}
+			data = append(data, d)
+		case "--data-urlencode":
+			d, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			if k, v, ok := strings.Cut(d, "="); ok {
+				data = append(data, k+"="+url.QueryEscape(v))
+			} else {
+				data = append(data, url.QueryEscape(d))
+			}
+		case "--json":
+			d, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			data = append(data, d)
+			r.AddHeader("Content-Type", "application/json")
+			r.AddHeader("Accept", "application/json")
+		case "-b", "--cookie":
+			c, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			if !strings.Contains(c, "=") {
+				return Request{}, fmt.Errorf("%s: cookie file %s: %w", op, c, ErrUnsupportedCurl)
+			}
+			r.Cookies = appendCookies(r.Cookies, c)
+		case "-A", "--user-agent":
+			v, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			r.AddHeader("User-Agent", v)
+		case "-e", "--referer":
+			v, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			r.AddHeader("Referer", v)
+		case "-u", "--user":
+			v, err := next()
+			if err != nil {
+				return Request{}, err
+			}
+			r.AddHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+		case "--url":
+			if r.URL, err = next(); err != nil {
+				return Request{}, err
+			}
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			head = true
+		case "-F", "--form", "-T", "--upload-file":
+			return Request{}, fmt.Errorf("%s: %s: %w", op, name, ErrUnsupportedCurl)
+		default:
+			if curlArgs[name] {
+				if _, err := next(); err != nil {
+					return Request{}, err
+				}
+			} else if !strings.HasPrefix(arg, "-") && r.URL == "" {
+				r.URL = arg
+			}
+		}
+	}
+
+	if r.URL == "" {
+		return Request{}, fmt.Errorf("%s: no URL", op)
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case get && body != "":
+		sep := "?"
+		if strings.Contains(r.URL, "?") {
+			sep = "&"
+		}
+		r.URL += sep + body
+	case body != "":
+		r.Body = body
+		if r.Method == "" {
+			r.Method = "POST"
+		}
+	}
+	if head && r.Method == "" {
+		r.Method = "HEAD"
+	}
+	if r.Method == "GET" {
+		r.Method = ""
+	}
+	return r, nil
+}
+
+func appendCookies(s, c string) string {
+	if s == "" {
+		return c
+	}
+	return s + "; " + c
+}
+
+// shellSplit splits cmd into words like sh does, without expansions.
+func shellSplit(cmd string) ([]string, error) {
+	var words []string
+	var w strings.Builder
+	inWord := false
+	for i := 0; i < len(cmd); i++ {
+		switch c := cmd[i]; c {
+		case ' ', '\t', '\n', '\r':
+			if inWord {
+				words = append(words, w.String())
+				w.Reset()
+				inWord = false
+			}
+		case '\\':
+			if i+1 < len(cmd) {
+				i++
+				if cmd[i] == '\n' {
+					continue
+				} else if cmd[i] == '\r' && i+1 < len(cmd) && cmd[i+1] == '\n' {
+					i++
+					continue
+				}
+				w.WriteByte(cmd[i])
+			}
+			inWord = true
+		case '\'':
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end == -1 {
+				return nil, errors.New("unterminated single quote")
+			}
+			w.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case '"':
+			i++
+			for ; i < len(cmd) && cmd[i] != '"'; i++ {
+				if cmd[i] == '\\' && i+1 < len(cmd) && strings.IndexByte("$`\"\\\n", cmd[i+1]) != -1 {
+					i++
+					if cmd[i] == '\n' {
+						continue
+					}
+				}
+				w.WriteByte(cmd[i])
+			}
+			if i == len(cmd) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case '$':
+			// Bash ANSI-C quoting, used by Chrome's "copy as cURL".
+			if i+1 < len(cmd) && cmd[i+1] == '\'' {
+				n, err := ansiQuoted(&w, cmd[i+2:])
+				if err != nil {
+					return nil, err
+				}
+				i += n + 2
+				inWord = true
+				continue
+			}
+			fallthrough
+		default:
+			w.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, w.String())
+	}
+	return words, nil
+}
+
+// ansiQuoted decodes the body of a $'...' string up to its closing quote
+// and returns how many bytes of s it used.
+func ansiQuoted(w *strings.Builder, s string) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return i, nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			i++
+			switch c := s[i]; c {
+			case 'n':
+				w.WriteByte('\n')
+			case 't':
+				w.WriteByte('\t')
+			case 'r':
+				w.WriteByte('\r')
+			case 'x', 'u':
+				n := 2
+				if c == 'u' {
+					n = 4
+				}
+				var r rune
+				if _, err := fmt.Sscanf(s[i+1:min(i+1+n, len(s))], "%x", &r); err != nil {
+					return 0, fmt.Errorf("bad escape \\%c", c)
+				}
+				if c == 'x' {
+					w.WriteByte(byte(r))
+				} else {
+					w.WriteRune(r)
+				}
+				i += n
+			default:
+				w.WriteByte(c)
+			}
+		default:
+			w.WriteByte(s[i])
+		}
+	}
+	return 0, errors.New("unterminated $' quote")
+}
+
+// Curl returns a curl command line that sends r, one option per line.
+func (r *Request) Curl() string {
+	var b strings.Builder
+	b.WriteString("curl")
+	switch {
+	case r.Method == "HEAD":
+		b.WriteString(" -I")
+	case r.Method == "" && r.Body != "":
+		// A body alone would make curl send a POST.
+		b.WriteString(" -X GET")
+	case r.Method != "" && !(r.Method == "POST" && r.Body != ""):
+		b.WriteString(" -X " + shellQuote(r.Method))
+	}
+	b.WriteString(" " + shellQuote(r.URL))
+	for _, h := range r.HeaderFields() {
+		b.WriteString(" \\\n  -H " + shellQuote(h[0]+": "+h[1]))
+	}
+	if r.Cookies != "" {
+		var pairs []string
+		for _, c := range r.CookieFields() {
+			pairs = append(pairs, c[0]+"="+c[1])
+		}
+		b.WriteString(" \\\n  -b " + shellQuote(strings.Join(pairs, "; ")))
+	}
+	if r.Body != "" {
+		b.WriteString(" \\\n  --data-raw " + shellQuote(r.Body))
+	}
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
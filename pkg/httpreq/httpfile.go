@@ -0,0 +1,157 @@
+package httpreq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Votline/Gurlf/pkg/core"
+)
+
+// ParseHTTPFile reads the request files of the VS Code REST Client and
+// JetBrains HTTP Client. Requests are separated by "###" lines, whose
+// text or a "# @name" comment names the section; requests that are
+// unnamed, or whose name is not a valid section name, are called
+// request1, request2 and so on. "@var = value" lines and
+// JetBrains response handlers are dropped; {{var}} references are kept
+// as written.
+func ParseHTTPFile(src []byte) ([]Request, error) {
+	const op = "httpreq.ParseHTTPFile"
+
+	var reqs []Request
+	for _, block := range splitRequests(string(src)) {
+		r, ok, err := parseHTTPRequest(block.lines)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", op, block.line, err)
+		} else if !ok {
+			continue
+		}
+
+		if !core.ValidName(r.Name) {
+			r.Name = block.name
+		}
+		if !core.ValidName(r.Name) {
+			r.Name = "request" + strconv.Itoa(len(reqs)+1)
+		}
+		reqs = append(reqs, r)
+	}
+	return reqs, nil
+}
+
+type httpBlock struct {
+	name  string
+	line  int
+	lines []string
+}
+
+func splitRequests(src string) []httpBlock {
+	blocks := []httpBlock{{line: 1}}
+	for i, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(line, "###") {
+			blocks = append(blocks, httpBlock{
+				name: strings.TrimSpace(strings.TrimLeft(line, "#")),
+				line: i + 2,
+			})
+			continue
+		}
+		b := &blocks[len(blocks)-1]
+		b.lines = append(b.lines, line)
+	}
+	if len(blocks[0].lines) == 0 || isBlank(blocks[0].lines) {
+		blocks = blocks[1:]
+	}
+	return blocks
+}
+
+func isBlank(lines []string) bool {
+	for _, l := range lines {
+		if t := strings.TrimSpace(l); t != "" && t[0] != '@' && !isComment(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func isComment(line string) bool {
+	t := strings.TrimSpace(line)
+	return strings.HasPrefix(t, "#") || strings.HasPrefix(t, "//")
+}
+
+// parseHTTPRequest reads the request line, the headers up to the first
+// blank line and the body. It reports false for a block with no request.
+func parseHTTPRequest(lines []string) (Request, bool, error) {
+	var r Request
+	i := 0
+	for ; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if t == "" {
+			continue
+		}
+		if t[0] == '@' {
+			continue
+		}
+		if !isComment(t) {
+			break
+		}
+		if name, ok := strings.CutPrefix(strings.TrimLeft(t, "#/ "), "@name"); ok {
+			r.Name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "="))
+		}
+	}
+	if i == len(lines) {
+		return r, false, nil
+	}
+
+	fields := strings.Fields(lines[i])
+	switch {
+	case len(fields) == 1:
+		r.URL = fields[0]
+	case len(fields) <= 3:
+		r.Method, r.URL = strings.ToUpper(fields[0]), fields[1]
+	default:
+		return r, false, fmt.Errorf("bad request line %q", lines[i])
+	}
+	if r.Method == "GET" {
+		r.Method = ""
+	}
+
+	// Query lines indented under the request line continue the URL.
+	for i++; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if t == "" || (t[0] != '?' && t[0] != '&') {
+			break
+		}
+		r.URL += t
+	}
+
+	for ; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if t == "" {
+			i++
+			break
+		}
+		if isComment(t) {
+			continue
+		}
+		name, value, ok := strings.Cut(t, ":")
+		if !ok {
+			return r, false, fmt.Errorf("bad header %q", t)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Cookie") {
+			r.Cookies = appendCookies(r.Cookies, strings.TrimSpace(value))
+			continue
+		}
+		r.AddHeader(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	var body []string
+	for ; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(t, "> ") || strings.HasPrefix(t, "<> ") {
+			break
+		}
+		body = append(body, lines[i])
+	}
+	r.Body = strings.TrimRight(strings.Join(body, "\n"), " \t\n")
+
+	return r, true, nil
+}
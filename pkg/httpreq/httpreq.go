@@ -0,0 +1,211 @@
+// Package httpreq maps the conventional gurl-cli request section to and
+// from net/http, curl command lines and .http request files:
+//
+//	[login]
+//	METHOD: POST
+//	URL: https://example.com/login
+//	HEADERS: `
+//	Content-Type: application/json
+//	Accept: */*
+//	`
+//	COOKIES: session=abc; theme=dark
+//	BODY: `{"user": "dev"}`
+//	[\login]
+//
+// HEADERS holds one "Name: value" pair per line and COOKIES holds
+// "name=value" pairs separated by ';' or line breaks.
+package httpreq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Votline/Gurlf/pkg/core"
+	"github.com/Votline/Gurlf/pkg/scanner"
+)
+
+// Request is a request section. An empty Method means GET.
+type Request struct {
+	Name    string `gurlf:"config_name"`
+	Method  string `gurlf:"METHOD,omitempty"`
+	URL     string `gurlf:"URL,required"`
+	Headers string `gurlf:"HEADERS,omitempty"`
+	Cookies string `gurlf:"COOKIES,omitempty"`
+	Body    string `gurlf:"BODY,omitempty"`
+}
+
+// Parse decodes the sections of src that have a URL key as Requests.
+// Other sections, such as shared variables, are skipped.
+func Parse(src []byte) ([]Request, error) {
+	const op = "httpreq.Parse"
+
+	s := scanner.ScannerPool.Get().(*scanner.Scanner)
+	defer s.Release()
+
+	ds, err := s.Scan(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ds = slices.DeleteFunc(ds, func(d scanner.Data) bool {
+		return !slices.ContainsFunc(d.Entries, func(ent scanner.Entry) bool {
+			return string(d.RawData[ent.KeyStart:ent.KeyEnd]) == "URL"
+		})
+	})
+
+	var reqs []Request
+	if err := core.UnmarshalAll(ds, &reqs); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Values point into src; copy them so the result outlives it.
+	for i := range reqs {
+		reqs[i] = reqs[i].clone()
+	}
+	return reqs, nil
+}
+
+// Marshal encodes reqs as gurlf sections. Every request needs a name
+// that is a valid section name.
+func Marshal(reqs []Request) ([]byte, error) {
+	const op = "httpreq.Marshal"
+
+	for _, r := range reqs {
+		if !core.ValidName(r.Name) {
+			return nil, fmt.Errorf("%s: %w %q", op, core.ErrInvalidName, r.Name)
+		}
+	}
+	res, err := core.Marshal(reqs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return res, nil
+}
+
+func (r Request) clone() Request {
+	return Request{
+		Name:    strings.Clone(r.Name),
+		Method:  strings.Clone(r.Method),
+		URL:     strings.Clone(r.URL),
+		Headers: strings.Clone(r.Headers),
+		Cookies: strings.Clone(r.Cookies),
+		Body:    strings.Clone(r.Body),
+	}
+}
+
+// HeaderFields returns the HEADERS lines as name and value pairs in file
+// order. Lines without a colon are skipped.
+func (r *Request) HeaderFields() [][2]string {
+	var res [][2]string
+	for line := range strings.Lines(r.Headers) {
+		name, value, ok := strings.Cut(line, ":")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			continue
+		}
+		res = append(res, [2]string{name, strings.TrimSpace(value)})
+	}
+	return res
+}
+
+// CookieFields returns the COOKIES pairs in file order.
+func (r *Request) CookieFields() [][2]string {
+	var res [][2]string
+	for _, pair := range strings.FieldsFunc(r.Cookies, func(c rune) bool {
+		return c == ';' || c == '\n' || c == '\r'
+	}) {
+		name, value, _ := strings.Cut(pair, "=")
+		if name = strings.TrimSpace(name); name != "" {
+			res = append(res, [2]string{name, strings.TrimSpace(value)})
+		}
+	}
+	return res
+}
+
+// AddHeader appends a "name: value" line to HEADERS.
+func (r *Request) AddHeader(name, value string) {
+	r.Headers = appendLine(r.Headers, name+": "+value)
+}
+
+// AddCookie appends a "name=value" pair to COOKIES.
+func (r *Request) AddCookie(name, value string) {
+	if r.Cookies != "" {
+		r.Cookies += "; "
+	}
+	r.Cookies += name + "=" + value
+}
+
+func appendLine(s, line string) string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s + line
+}
+
+// NewHTTPRequest builds the *http.Request that r describes.
+func (r *Request) NewHTTPRequest(ctx context.Context) (*http.Request, error) {
+	const op = "httpreq.NewHTTPRequest"
+
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, h := range r.HeaderFields() {
+		req.Header.Add(h[0], h[1])
+	}
+	for _, c := range r.CookieFields() {
+		req.AddCookie(&http.Cookie{Name: c[0], Value: c[1]})
+	}
+	return req, nil
+}
+
+// FromHTTPRequest returns the section describing req. It reads the body
+// and replaces it with a copy, so req can still be sent.
+func FromHTTPRequest(req *http.Request) (Request, error) {
+	const op = "httpreq.FromHTTPRequest"
+
+	r := Request{Method: req.Method, URL: req.URL.String()}
+	if r.Method == http.MethodGet {
+		r.Method = ""
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if name == "Cookie" {
+			continue
+		}
+		for _, v := range req.Header[name] {
+			r.AddHeader(name, v)
+		}
+	}
+	for _, c := range req.Cookies() {
+		r.AddCookie(c.Name, c.Value)
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return Request{}, fmt.Errorf("%s: %w", op, err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		r.Body = string(b)
+	}
+	return r, nil
+}
@@ -0,0 +1,208 @@
+package httpreq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Votline/Gurlf/pkg/core"
+)
+
+const section = "[login]\n" +
+	"METHOD: POST\n" +
+	"URL: https://example.com/login?next=/home\n" +
+	"HEADERS: ``\nContent-Type: application/json\nAccept: */*\n``\n" +
+	"COOKIES: session=abc; theme=dark\n" +
+	"BODY: `{\"user\": \"dev's\"}`\n" +
+	"[\\login]\n"
+
+func TestHTTPRequest(t *testing.T) {
+	reqs, err := Parse([]byte(section))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reqs) != 1 || reqs[0].Name != "login" {
+		t.Fatalf("unexpected requests: %+v", reqs)
+	}
+
+	req, err := reqs[0].NewHTTPRequest(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Method != "POST" || req.URL.Query().Get("next") != "/home" {
+		t.Errorf("unexpected request line: %s %s", req.Method, req.URL)
+	}
+	if req.Header.Get("Content-Type") != "application/json" || req.Header.Get("Accept") != "*/*" {
+		t.Errorf("unexpected headers: %v", req.Header)
+	}
+	if c, err := req.Cookie("theme"); err != nil || c.Value != "dark" {
+		t.Errorf("unexpected cookie: %v, %v", c, err)
+	}
+
+	back, err := FromHTTPRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	back.Name = "login"
+	want := reqs[0]
+	want.Headers = "Accept: */*\nContent-Type: application/json"
+	if back != want {
+		t.Errorf("expected\n%+v\ngot\n%+v", want, back)
+	}
+	if b, _ := io.ReadAll(req.Body); string(b) != want.Body {
+		t.Errorf("body was not restored: %q", b)
+	}
+
+	out, err := Marshal(reqs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, err := Parse(out); err != nil || !reflect.DeepEqual(again, reqs) {
+		t.Errorf("round trip mismatch: %v\n%s", err, out)
+	}
+}
+
+func TestParseSkipsOtherSections(t *testing.T) {
+	reqs, err := Parse([]byte("[common]\nHOST: example.com\n[\\common]\n" + section))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reqs) != 1 || reqs[0].Name != "login" {
+		t.Errorf("expected only the login request, got %+v", reqs)
+	}
+}
+
+func TestMarshalInvalidName(t *testing.T) {
+	for _, name := range []string{"", "a]b", "\\a", "a\nb"} {
+		out, err := Marshal([]Request{{Name: name, URL: "https://example.com"}})
+		if !errors.Is(err, core.ErrInvalidName) {
+			t.Errorf("%q: expected ErrInvalidName, got %v", name, err)
+		}
+		if out != nil {
+			t.Errorf("%q: expected no output, got %q", name, out)
+		}
+	}
+}
+
+func TestCurl(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want Request
+	}{
+		{
+			cmd: `curl 'https://example.com/login?next=/home' \
+  -H 'Content-Type: application/json' \
+  -H "Accept: */*" \
+  -b 'session=abc; theme=dark' \
+  --data-raw $'{"user": "dev\'s"}' --compressed -s`,
+			want: Request{
+				Method:  "POST",
+				URL:     "https://example.com/login?next=/home",
+				Headers: "Content-Type: application/json\nAccept: */*",
+				Cookies: "session=abc; theme=dark",
+				Body:    `{"user": "dev's"}`,
+			},
+		},
+		{
+			cmd:  `curl -XDELETE --url=https://example.com/x -o out.txt -H 'Cookie: a=1'`,
+			want: Request{Method: "DELETE", URL: "https://example.com/x", Cookies: "a=1"},
+		},
+		{
+			cmd:  `curl -G https://example.com/s -d q=go --data-urlencode 'tag=a b' -u dev:pw`,
+			want: Request{URL: "https://example.com/s?q=go&tag=a+b", Headers: "Authorization: Basic ZGV2OnB3"},
+		},
+		{
+			cmd:  `curl -X GET https://example.com/search -d '{"q": "go"}'`,
+			want: Request{URL: "https://example.com/search", Body: `{"q": "go"}`},
+		},
+	}
+
+	for i, tt := range tests {
+		got, err := ParseCurl(tt.cmd)
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("[%d]: expected\n%+v\ngot\n%+v", i, tt.want, got)
+		}
+
+		again, err := ParseCurl(got.Curl())
+		if err != nil || again != got {
+			t.Errorf("[%d]: export does not parse back: %v\n%s", i, err, got.Curl())
+		}
+	}
+
+	for _, cmd := range []string{`curl -F file=@a.txt https://x`, `curl -d @body.json https://x`, `curl 'https://x`} {
+		if _, err := ParseCurl(cmd); err == nil {
+			t.Errorf("expected error for %s", cmd)
+		}
+	}
+}
+
+func TestParseHTTPFile(t *testing.T) {
+	src := strings.ReplaceAll(`@host = example.com
+
+### Login
+POST https://{{host}}/login HTTP/1.1
+Content-Type: application/json
+Cookie: session=abc
+
+{"user": "dev"}
+
+> {% client.global.set("token", response.body.token); %}
+
+###
+# @name profile
+GET https://{{host}}/me
+    ?fields=name
+    &lang=en
+Authorization: Bearer {{token}}
+
+###
+https://{{host}}/health
+
+### [admin] status
+# @name admin]
+GET https://{{host}}/admin
+
+### \\logout
+POST https://{{host}}/logout
+`, "\n", "\r\n")
+
+	reqs, err := ParseHTTPFile([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Request{
+		{
+			Name: "Login", Method: "POST", URL: "https://{{host}}/login",
+			Headers: "Content-Type: application/json", Cookies: "session=abc",
+			Body: `{"user": "dev"}`,
+		},
+		{
+			Name: "profile", URL: "https://{{host}}/me?fields=name&lang=en",
+			Headers: "Authorization: Bearer {{token}}",
+		},
+		{Name: "request3", URL: "https://{{host}}/health"},
+		{Name: "request4", URL: "https://{{host}}/admin"},
+		{Name: "request5", Method: "POST", URL: "https://{{host}}/logout"},
+	}
+	if !reflect.DeepEqual(reqs, want) {
+		t.Errorf("expected\n%+v\ngot\n%+v", want, reqs)
+	}
+}
+
+func TestNewHTTPRequestError(t *testing.T) {
+	r := Request{Method: "BAD METHOD", URL: "https://example.com"}
+	if _, err := r.NewHTTPRequest(context.Background()); err == nil {
+		t.Error("expected error for invalid method")
+	}
+	r = Request{URL: "://bad"}
+	if _, err := r.NewHTTPRequest(context.Background()); err == nil {
+		t.Error("expected error for invalid URL")
+	}
+}
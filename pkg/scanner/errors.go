@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+type ErrorKind uint8
+
+const (
+	UnclosedSection ErrorKind = iota + 1
+	MissingBracket
+	UnterminatedBlock
+	MissingValueEnd
+	MissingSectionStart
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case UnclosedSection:
+		return "unclosed section"
+	case MissingBracket:
+		return "missing ']'"
+	case UnterminatedBlock:
+		return "unterminated backtick block"
+	case MissingValueEnd:
+		return "missing value end"
+	case MissingSectionStart:
+		return "missing section start"
+	}
+	return fmt.Sprintf("ErrorKind(%d)", uint8(k))
+}
+
+// SyntaxError describes malformed input found by Scan.
+// Offset is a byte offset into the scanned data, Line and Col are 1-based.
+type SyntaxError struct {
+	Kind    ErrorKind
+	Section string
+	Offset  int
+	Line    int
+	Col     int
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Section != "" {
+		return fmt.Sprintf("%d:%d: %s in section %q", e.Line, e.Col, e.Kind, e.Section)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Kind)
+}
+
+// ErrorList is every problem ScanRecover found, in input order.
+type ErrorList []*SyntaxError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+var errNoKey = errors.New("no key value start")
+
+func locate(err error, src []byte, base int, section []byte) error {
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		return err
+	}
+
+	se.Offset += base
+	if se.Section == "" && section != nil {
+		se.Section = string(section)
+	}
+	if src != nil {
+		se.Line, se.Col = position(src, se.Offset)
+	}
+
+	return err
+}
+
+func position(src []byte, off int) (line, col int) {
+	off = min(max(off, 0), len(src))
+	line = bytes.Count(src[:off], []byte{'\n'}) + 1
+	col = off - bytes.LastIndexByte(src[:off], '\n')
+	return line, col
+}
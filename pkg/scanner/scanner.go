@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"sync"
 )
@@ -11,14 +12,32 @@ type Entry struct {
 	ValStart, ValEnd int
 }
 
+// Comment is a line starting with '#' inside a section. Start and End
+// bound it in RawData, from the '#' to the end of the line.
+type Comment struct {
+	Start, End int
+}
+
+// Data is a scanned section. Offset and Line are the position of RawData
+// in the scanned input and Children holds the sections nested inside it.
 type Data struct {
-	Name    []byte
-	RawData []byte
-	Entries []Entry
+	Name     []byte
+	RawData  []byte
+	Entries  []Entry
+	Comments []Comment
+	Offset   int
+	Line     int
+	Children []Data
+}
+
+// LineOf returns the input line holding RawData[off].
+func (d Data) LineOf(off int) int {
+	return d.Line + bytes.Count(d.RawData[:off], []byte{'\n'})
 }
 
 type Scanner struct {
 	enBuf []Entry
+	cmBuf []Comment
 	dtBuf []Data
 }
 
@@ -26,67 +45,201 @@ var ScannerPool = sync.Pool{
 	New: func() any {
 		return &Scanner{
 			enBuf: make([]Entry, 0, 256),
+			cmBuf: make([]Comment, 0, 16),
 			dtBuf: make([]Data, 0, 32),
 		}
 	},
 }
 
+// Scan returns every section of d. Name and RawData point into d, while
+// Entries point into buffers owned by s: they stay valid only until the
+// next call on s or until s is released. Use Detach to keep them longer.
 func (s *Scanner) Scan(d []byte) ([]Data, error) {
-	const op = "scanner.Scan"
-
 	s.enBuf = s.enBuf[:0]
+	s.cmBuf = s.cmBuf[:0]
 	s.dtBuf = s.dtBuf[:0]
-	for len(d) > 0 {
-		name, conStart, err := findStart(d)
+	for base, line := 0, 1; base < len(d); {
+		dt, n, err := s.next(d, base, line)
 		if err != nil {
-			return nil, fmt.Errorf("%s: start idx: %w", op, err)
-		} else if name == nil {
-			return s.dtBuf, nil
+			return nil, err
+		} else if n == 0 {
+			break
 		}
 
-		conEnd, totalConsumed, err := findEnd(name, d[conStart:])
-		if err != nil {
-			return nil, fmt.Errorf("%s: end idx: %w", op, err)
-		}
+		s.dtBuf = append(s.dtBuf, dt)
+		line += bytes.Count(d[base:base+n], []byte{'\n'})
+		base += n
+	}
 
-		start := len(s.enBuf)
-		s.emit(d[conStart : conStart+conEnd])
-		end := len(s.enBuf)
+	res := make([]Data, len(s.dtBuf))
+	copy(res, s.dtBuf)
+	s.dtBuf = s.dtBuf[:0]
 
-		s.dtBuf = append(s.dtBuf, Data{
-			Name:    name,
-			RawData: d[conStart : conStart+conEnd],
-			Entries: s.enBuf[start:end],
-		})
+	return res, nil
+}
 
-		d = d[conStart+totalConsumed:]
+// ScanRecover is like Scan but does not stop at malformed input. After an
+// error it skips to the end of the broken section or, when that is not
+// known, to the next line opening a section. It returns the sections that
+// scanned cleanly and, if anything was skipped, an ErrorList.
+func (s *Scanner) ScanRecover(d []byte) ([]Data, error) {
+	s.enBuf = s.enBuf[:0]
+	s.cmBuf = s.cmBuf[:0]
+	s.dtBuf = s.dtBuf[:0]
+
+	var errs ErrorList
+	for base, line := 0, 1; base < len(d); {
+		dt, n, err := s.next(d, base, line)
+		if err != nil {
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				return nil, err
+			}
+			errs = append(errs, se)
+			if n == 0 {
+				n = resync(d, max(se.Offset, base)) - base
+			}
+		} else if n == 0 {
+			break
+		} else {
+			s.dtBuf = append(s.dtBuf, dt)
+		}
+
+		line += bytes.Count(d[base:base+n], []byte{'\n'})
+		base += n
 	}
 
 	res := make([]Data, len(s.dtBuf))
 	copy(res, s.dtBuf)
 	s.dtBuf = s.dtBuf[:0]
 
+	if errs != nil {
+		return res, errs
+	}
 	return res, nil
 }
 
-func findStart(d []byte) (name []byte, nextIdx int, err error) {
-	const op = "scanner.findName"
+// resync returns the start of the first line after the one holding
+// d[from] that opens a section, or len(d).
+func resync(d []byte, from int) int {
+	for i := lineEnd(d, from) + 1; i < len(d); i = lineEnd(d, i) + 1 {
+		j := i
+		for j < len(d) && (d[j] == ' ' || d[j] == '\t') {
+			j++
+		}
+		if j+1 < len(d) && d[j] == '[' && d[j+1] != '\\' {
+			return i
+		}
+	}
+	return len(d)
+}
+
+// Next scans the first section of d and reports how many bytes it consumed.
+// It returns zero bytes and no error when d holds nothing but whitespace.
+// On a malformed entry the byte count still covers the broken section.
+// The returned entries are only valid until the next call on s.
+func (s *Scanner) Next(d []byte) (Data, int, error) {
+	s.enBuf = s.enBuf[:0]
+	s.cmBuf = s.cmBuf[:0]
+	return s.next(d, 0, 1)
+}
+
+// Release resets s and returns it to ScannerPool.
+// Data returned by s must not be used afterwards unless it was detached.
+func (s *Scanner) Release() {
+	s.enBuf = s.enBuf[:0]
+	s.cmBuf = s.cmBuf[:0]
+	s.dtBuf = s.dtBuf[:0]
+	ScannerPool.Put(s)
+}
+
+// Detach moves the entries and comments of ds and of their children into
+// memory no Scanner will reuse. Name and RawData keep referencing the
+// scanned input.
+func Detach(ds []Data) []Data {
+	nEn, nCm := count(ds)
+	detach(ds, make([]Entry, 0, nEn), make([]Comment, 0, nCm))
+	return ds
+}
+
+func count(ds []Data) (entries, comments int) {
+	for _, d := range ds {
+		en, cm := count(d.Children)
+		entries += len(d.Entries) + en
+		comments += len(d.Comments) + cm
+	}
+	return entries, comments
+}
+
+func detach(ds []Data, ens []Entry, cms []Comment) ([]Entry, []Comment) {
+	for i := range ds {
+		start := len(ens)
+		ens = append(ens, ds[i].Entries...)
+		ds[i].Entries = ens[start:len(ens):len(ens)]
+
+		start = len(cms)
+		cms = append(cms, ds[i].Comments...)
+		ds[i].Comments = cms[start:len(cms):len(cms)]
+
+		ens, cms = detach(ds[i].Children, ens, cms)
+	}
+	return ens, cms
+}
 
+func (s *Scanner) next(src []byte, base, line int) (Data, int, error) {
+	const op = "scanner.Scan"
+
+	d := src[base:]
+	name, conStart, err := findStart(d)
+	if err != nil {
+		return Data{}, 0, fmt.Errorf("%s: start idx: %w", op, locate(err, src, base, nil))
+	} else if name == nil {
+		return Data{}, 0, nil
+	}
+
+	conEnd, totalConsumed, err := findEnd(name, d[conStart:])
+	if err != nil {
+		return Data{}, 0, fmt.Errorf("%s: end idx: %w", op, locate(err, src, base+conStart-len(name)-2, name))
+	}
+
+	dt := Data{
+		Name:    name,
+		RawData: d[conStart : conStart+conEnd],
+		Offset:  base + conStart,
+		Line:    line + bytes.Count(d[:conStart], []byte{'\n'}),
+	}
+	if err := s.emit(&dt); err != nil {
+		// The section is known to end here, so recovery can resume after it.
+		return Data{}, conStart + totalConsumed, fmt.Errorf("%s: emit: %w", op, locate(err, src, 0, name))
+	}
+
+	return dt, conStart + totalConsumed, nil
+}
+
+func findStart(d []byte) (name []byte, nextIdx int, err error) {
 	i := 0
-	for i < len(d) && isSpace(d[i]) {
-		i++
+	for i < len(d) {
+		if isSpace(d[i]) {
+			i++
+		} else if d[i] == '#' {
+			i = lineEnd(d, i)
+		} else {
+			break
+		}
 	}
 	if i == len(d) {
 		return nil, 0, nil
 	}
 
-	start := bytes.IndexByte(d, byte('['))
-	if start == -1 {
-		return nil, 0, fmt.Errorf("%s: start idx: no name start", op)
+	start := bytes.IndexByte(d[i:], byte('['))
+	if start != -1 {
+		start += i
+	} else {
+		return nil, 0, &SyntaxError{Kind: MissingSectionStart, Offset: i}
 	}
-	end := bytes.IndexByte(d[start:], byte(']'))
+	end := bytes.IndexByte(d[start:lineEnd(d, start)], byte(']'))
 	if end == -1 {
-		return nil, 0, fmt.Errorf("%s: end idx: no name end", op)
+		return nil, 0, &SyntaxError{Kind: MissingBracket, Offset: start}
 	}
 
 	name = d[start+1 : start+end]
@@ -95,26 +248,104 @@ func findStart(d []byte) (name []byte, nextIdx int, err error) {
 }
 
 func findEnd(n []byte, d []byte) (contentEnd int, totalConsumed int, err error) {
-	const op = "scanner.findEnd"
-
+	colon, lineStart, depth := false, true, 0
 	for i := 0; i+2+len(n) <= len(d); i++ {
-		if d[i] == '[' && d[i+1] == '\\' {
-			if bytes.Equal(d[i+2:i+2+len(n)], n) && i+2+len(n) < len(d) && d[i+2+len(n)] == ']' {
-				return i, i + 3 + len(n), nil
+		switch d[i] {
+		case '\n':
+			colon, lineStart = false, true
+			continue
+		case '#':
+			if lineStart {
+				i = lineEnd(d, i) - 1
+				continue
+			}
+		case ':':
+			if colon {
+				break
+			}
+			colon = true
+
+			j := i + 1
+			for j < len(d) && d[j] == ' ' {
+				j++
+			}
+			if j+1 < len(d) && d[j] == '`' {
+				if _, _, end, ok := blockEnd(d, j); ok {
+					i = end - 1
+				}
+			}
+		case '[':
+			if d[i+1] == '\\' && bytes.Equal(d[i+2:i+2+len(n)], n) &&
+				i+2+len(n) < len(d) && d[i+2+len(n)] == ']' {
+				if depth == 0 {
+					return i, i + 3 + len(n), nil
+				}
+				depth--
+			} else if lineStart && bytes.Equal(d[i+1:i+1+len(n)], n) && d[i+1+len(n)] == ']' {
+				depth++
 			}
 		}
+
+		if !isSpace(d[i]) {
+			lineStart = false
+		}
 	}
 
-	return -1, -1, fmt.Errorf("%s: no end", op)
+	return -1, -1, &SyntaxError{Kind: UnclosedSection}
 }
 
-func (s *Scanner) emit(cfgData []byte) {
+// emit fills the entries, comments and nested sections of dt from its
+// RawData. Its own entries and comments are appended to the scanner
+// buffers before those of its children.
+func (s *Scanner) emit(dt *Data) error {
+	var kids []Data
+	off, line, counted := dt.Offset, dt.Line, 0
+	en, cm := len(s.enBuf), len(s.cmBuf)
 	offset := 0
-	curr := cfgData
+	curr := dt.RawData
 	for len(curr) > 0 {
+		i := 0
+		for i < len(curr) && isSpace(curr[i]) {
+			i++
+		}
+		if i < len(curr) && curr[i] == '#' {
+			end := lineEnd(curr, i)
+			cEnd := end
+			if curr[cEnd-1] == '\r' {
+				cEnd--
+			}
+			s.cmBuf = append(s.cmBuf, Comment{Start: offset + i, End: offset + cEnd})
+
+			curr = curr[end:]
+			offset += end
+			continue
+		}
+		if name, conStart := findChild(curr[i:]); name != nil {
+			conEnd, totalConsumed, err := findEnd(name, curr[i+conStart:])
+			if err != nil {
+				return locate(err, nil, off+offset+i, name)
+			}
+
+			line += bytes.Count(dt.RawData[counted:offset+i+conStart], []byte{'\n'})
+			counted = offset + i + conStart
+			kids = append(kids, Data{
+				Name:    name,
+				RawData: curr[i+conStart : i+conStart+conEnd],
+				Offset:  off + offset + i + conStart,
+				Line:    line,
+			})
+
+			consumed := i + conStart + totalConsumed
+			curr = curr[consumed:]
+			offset += consumed
+			continue
+		}
+
 		kS, kE, vS, vE, consumed, err := findKeyValue(curr)
-		if err != nil {
+		if errors.Is(err, errNoKey) {
 			break
+		} else if err != nil {
+			return locate(err, nil, off+offset, nil)
 		}
 
 		s.enBuf = append(s.enBuf, Entry{
@@ -125,14 +356,43 @@ func (s *Scanner) emit(cfgData []byte) {
 		curr = curr[consumed:]
 		offset += consumed
 	}
+
+	dt.Entries = s.enBuf[en:]
+	dt.Comments = s.cmBuf[cm:]
+	for i := range kids {
+		if err := s.emit(&kids[i]); err != nil {
+			return locate(err, nil, 0, kids[i].Name)
+		}
+	}
+	dt.Children = kids
+
+	return nil
 }
 
-func findKeyValue(d []byte) (keyS, keyE, valS, valE int, contentEnd int, err error) {
-	const op = "scanner.findKeyValue"
+// findChild reports the name of a nested section header at the start of d
+// and the index just past its closing bracket.
+func findChild(d []byte) (name []byte, nextIdx int) {
+	if len(d) < 2 || d[0] != '[' || d[1] == '\\' {
+		return nil, 0
+	}
+
+	end := bytes.IndexByte(d, ']')
+	if end == -1 || bytes.IndexByte(d[:end], '\n') != -1 {
+		return nil, 0
+	}
+	for i := end + 1; i < len(d) && d[i] != '\n'; i++ {
+		if !isSpace(d[i]) {
+			return nil, 0
+		}
+	}
+
+	return d[1:end], end + 1
+}
 
+func findKeyValue(d []byte) (keyS, keyE, valS, valE int, contentEnd int, err error) {
 	start := bytes.IndexByte(d, ':')
 	if start == -1 {
-		return 0, 0, 0, 0, 0, fmt.Errorf("%s: start idx: no key value start", op)
+		return 0, 0, 0, 0, 0, errNoKey
 	}
 	seg := d[:start]
 
@@ -143,7 +403,7 @@ func findKeyValue(d []byte) (keyS, keyE, valS, valE int, contentEnd int, err err
 	keyS = i
 
 	j := len(seg) - 1
-	for j < len(seg) && isSpace(seg[j]) {
+	for j >= keyS && isSpace(seg[j]) {
 		j--
 	}
 	keyE = j + 1
@@ -154,36 +414,16 @@ func findKeyValue(d []byte) (keyS, keyE, valS, valE int, contentEnd int, err err
 	}
 
 	if start+1 < len(d) && d[start] == '`' {
-		valE, firstAny := -1, -1
-		for i := start + 1; i < len(d); i++ {
-			if d[i] == '`' {
-				if d[i-1] == '\n' && (i+1 >= len(d) || d[i+1] == '\n' || d[i+1] == '\r') {
-					valE = i
-					break
-				}
-				if firstAny == -1 {
-					firstAny = i
-				}
-			}
-		}
-		if valE == -1 {
-			valE = firstAny
+		valS, valE, end, ok := blockEnd(d, start)
+		if !ok {
+			return 0, 0, 0, 0, 0, &SyntaxError{Kind: UnterminatedBlock, Offset: start}
 		}
-
-		valS = start + 1
-		lineEnd := bytes.IndexByte(d[valE:], '\n')
-
-		if lineEnd == -1 {
-			return keyS, keyE, valS, valE, valE, nil
-		}
-
-		return keyS, keyE, valS, valE, valE + lineEnd, nil
+		return keyS, keyE, valS, valE, end, nil
 	}
 
 	end := bytes.Index(d[start:], []byte("\n"))
 	if end == -1 {
-		return 0, 0, 0, 0, 0,
-			fmt.Errorf("%s: end idx: no value end", op)
+		return 0, 0, 0, 0, 0, &SyntaxError{Kind: MissingValueEnd, Offset: keyS}
 	}
 
 	valS = start
@@ -192,6 +432,70 @@ func findKeyValue(d []byte) (keyS, keyE, valS, valE int, contentEnd int, err err
 	return keyS, keyE, valS, valE, end + start + 1, nil
 }
 
+// blockEnd finds the bounds of the backtick value opened at d[open].
+// A run of two or more backticks followed by a newline opens a fence that
+// is closed by a line holding exactly the same run; the newlines next to
+// the fences are not part of the value. A single backtick closes at the
+// last backtick of its own line or, when there is none, at the first
+// backtick isolated on a line. end is the index of the newline after the
+// closing delimiter, or len(d).
+func blockEnd(d []byte, open int) (valS, valE, end int, ok bool) {
+	k := open
+	for k < len(d) && d[k] == '`' {
+		k++
+	}
+	k -= open
+
+	if k >= 2 {
+		nl := open + k
+		if nl+1 < len(d) && d[nl] == '\r' && d[nl+1] == '\n' {
+			nl++
+		}
+		if nl < len(d) && d[nl] == '\n' {
+			valS = nl + 1
+			for i := valS; i < len(d); i++ {
+				if d[i] == '\n' && isFence(d[i+1:], k) {
+					return valS, i, lineEnd(d, i+1+k), true
+				}
+			}
+			return 0, 0, 0, false
+		}
+	}
+
+	valS = open + 1
+	eol := lineEnd(d, valS)
+	if last := bytes.LastIndexByte(d[valS:eol], '`'); last != -1 {
+		return valS, valS + last, eol, true
+	}
+
+	for i := eol + 1; i < len(d); i++ {
+		if d[i] == '`' && d[i-1] == '\n' && isFence(d[i:], 1) {
+			return valS, i, lineEnd(d, i), true
+		}
+	}
+
+	return 0, 0, 0, false
+}
+
+func isFence(d []byte, k int) bool {
+	if len(d) < k {
+		return false
+	}
+	for _, c := range d[:k] {
+		if c != '`' {
+			return false
+		}
+	}
+	return len(d) == k || d[k] == '\n' || d[k] == '\r'
+}
+
+func lineEnd(d []byte, from int) int {
+	if i := bytes.IndexByte(d[from:], '\n'); i != -1 {
+		return from + i
+	}
+	return len(d)
+}
+
 func isSpace(r byte) bool {
 	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f'
 }
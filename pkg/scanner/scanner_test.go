@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -30,6 +31,267 @@ func TestScan(t *testing.T) {
 	}
 }
 
+func TestScanNested(t *testing.T) {
+	cfgData := []byte(`[request]
+ID: 1
+[headers]
+Accept: json
+[auth]
+User: dev
+[\auth]
+[\headers]
+BODY: ` + "`" + `
+[not_a_child]
+` + "`" + `
+[request]
+ID: 2
+[\request]
+[\request]
+`)
+	s := ScannerPool.Get().(*Scanner)
+	defer s.Release()
+
+	res, err := s.Scan(cfgData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res) != 1 || len(res[0].Children) != 2 {
+		t.Fatalf("unexpected tree: %+v", res)
+	}
+
+	req := res[0]
+	headers := req.Children[0]
+	tests := []struct {
+		d    Data
+		name string
+		line int
+		keys []string
+	}{
+		{req, "request", 1, []string{"ID", "BODY"}},
+		{headers, "headers", 3, []string{"Accept"}},
+		{headers.Children[0], "auth", 5, []string{"User"}},
+		{req.Children[1], "request", 12, []string{"ID"}},
+	}
+
+	for i, tt := range tests {
+		if string(tt.d.Name) != tt.name {
+			t.Errorf("[%d]: expected name %q, got %q", i, tt.name, tt.d.Name)
+		}
+		if string(cfgData[tt.d.Offset:tt.d.Offset+len(tt.d.RawData)]) != string(tt.d.RawData) {
+			t.Errorf("[%d]: offset %d does not point at raw data", i, tt.d.Offset)
+		}
+		if tt.d.Line != tt.line {
+			t.Errorf("[%d]: expected line %d, got %d", i, tt.line, tt.d.Line)
+		}
+		if len(tt.d.Entries) != len(tt.keys) {
+			t.Errorf("[%d]: expected %d entries, got %d", i, len(tt.keys), len(tt.d.Entries))
+			continue
+		}
+		for j, ent := range tt.d.Entries {
+			if key := string(tt.d.RawData[ent.KeyStart:ent.KeyEnd]); key != tt.keys[j] {
+				t.Errorf("[%d][%d]: expected key %q, got %q", i, j, tt.keys[j], key)
+			}
+		}
+	}
+}
+
+func TestScanComments(t *testing.T) {
+	cfgData := []byte("# see [other]: it is older\n" +
+		"[request]\n" +
+		"# METHOD: POST\n" +
+		"ID: 1\n" +
+		"  # [\\request] is not the end\r\n" +
+		"BODY: `\n# kept\n`\n" +
+		"[\\request]\n" +
+		"# done\n")
+	s := ScannerPool.Get().(*Scanner)
+	defer s.Release()
+
+	res, err := s.Scan(cfgData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res) != 1 || string(res[0].Name) != "request" {
+		t.Fatalf("unexpected sections: %+v", res)
+	}
+
+	d := res[0]
+	keys := []string{"ID", "BODY"}
+	if len(d.Entries) != len(keys) {
+		t.Fatalf("expected %d entries, got %d", len(keys), len(d.Entries))
+	}
+	for i, ent := range d.Entries {
+		if key := string(d.RawData[ent.KeyStart:ent.KeyEnd]); key != keys[i] {
+			t.Errorf("[%d]: expected key %q, got %q", i, keys[i], key)
+		}
+	}
+	if val := string(d.RawData[d.Entries[1].ValStart:d.Entries[1].ValEnd]); val != "\n# kept\n" {
+		t.Errorf("block value mismatch: %q", val)
+	}
+
+	comments := []string{"# METHOD: POST", "# [\\request] is not the end"}
+	if len(d.Comments) != len(comments) {
+		t.Fatalf("expected %d comments, got %d", len(comments), len(d.Comments))
+	}
+	for i, c := range d.Comments {
+		if got := string(d.RawData[c.Start:c.End]); got != comments[i] {
+			t.Errorf("[%d]: expected comment %q, got %q", i, comments[i], got)
+		}
+	}
+}
+
+func TestScanSyntaxError(t *testing.T) {
+	tests := []struct {
+		input   string
+		kind    ErrorKind
+		section string
+		line    int
+		col     int
+	}{
+		{"[a]\nID: 1\n[\\a]\n\n[b]\nID: 2\n", UnclosedSection, "b", 5, 1},
+		{"[a]\nID: 1\n[\\a]\n  [b\nID: 2\n", MissingBracket, "", 4, 3},
+		{"[a\nK: v\n[b]\nK: v\n[\\b]\n", MissingBracket, "", 1, 1},
+		{"[a]\nID: 1\nBODY: `\n  text\n[\\a]", UnterminatedBlock, "a", 3, 7},
+		{"[a]\nID: 1\nUser: dev[\\a]", MissingValueEnd, "a", 3, 1},
+		{"[a]\nID: 1\n[\\a]\n\n  garbage\n", MissingSectionStart, "", 5, 3},
+		{"[a]\nID: 1\n[b]\nX: 1\n[\\a]", UnclosedSection, "b", 3, 1},
+		{"[a]\n[b]\nX: `\n[\\b]\n[\\a]", UnterminatedBlock, "b", 3, 4},
+	}
+
+	s := ScannerPool.Get().(*Scanner)
+	defer ScannerPool.Put(s)
+
+	for i, tt := range tests {
+		_, err := s.Scan([]byte(tt.input))
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Fatalf("[%d]: expected *SyntaxError, got %v", i, err)
+		}
+
+		if se.Kind != tt.kind {
+			t.Errorf("[%d]: expected kind %q, got %q", i, tt.kind, se.Kind)
+		}
+		if se.Section != tt.section {
+			t.Errorf("[%d]: expected section %q, got %q", i, tt.section, se.Section)
+		}
+		if se.Line != tt.line || se.Col != tt.col {
+			t.Errorf("[%d]: expected %d:%d, got %d:%d",
+				i, tt.line, tt.col, se.Line, se.Col)
+		}
+	}
+}
+
+func TestScanRecover(t *testing.T) {
+	input := "[a]\nID: 1\n[\\a]\n" +
+		"[b]\nID: 2\n" +
+		"[c]\nUser: dev[\\c]\n" +
+		"[d]\nID: 4\n[\\d]\n" +
+		"  [e\n"
+
+	s := ScannerPool.Get().(*Scanner)
+	defer s.Release()
+
+	res, err := s.ScanRecover([]byte(input))
+
+	var names []string
+	for _, d := range res {
+		names = append(names, string(d.Name))
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "d" {
+		t.Errorf("expected sections [a d], got %v", names)
+	}
+	if res[1].Line != 8 || len(res[1].Entries) != 1 {
+		t.Errorf("unexpected section d: %+v", res[1])
+	}
+
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected ErrorList, got %v", err)
+	}
+	wants := []struct {
+		kind ErrorKind
+		line int
+	}{
+		{UnclosedSection, 4},
+		{MissingValueEnd, 7},
+		{MissingBracket, 11},
+	}
+	if len(list) != len(wants) {
+		t.Fatalf("expected %d errors, got %d: %v", len(wants), len(list), list)
+	}
+	for i, w := range wants {
+		if list[i].Kind != w.kind || list[i].Line != w.line {
+			t.Errorf("[%d]: expected %s at line %d, got %s at line %d",
+				i, w.kind, w.line, list[i].Kind, list[i].Line)
+		}
+	}
+
+	var se *SyntaxError
+	if !errors.As(err, &se) || se != list[0] {
+		t.Errorf("expected errors.As to find the first error, got %v", se)
+	}
+
+	if _, err := s.ScanRecover([]byte("[a]\nID: 1\n[\\a]\n")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestScanRecoverHeader(t *testing.T) {
+	s := ScannerPool.Get().(*Scanner)
+	defer s.Release()
+
+	res, err := s.ScanRecover([]byte("[a\nK: v\n[b]\nK: v\n[\\b]\n"))
+	if len(res) != 1 || string(res[0].Name) != "b" {
+		t.Errorf("expected section b after the broken header, got %v", res)
+	}
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Kind != MissingBracket || se.Line != 1 || se.Col != 1 {
+		t.Errorf("expected missing ']' at 1:1, got %v", err)
+	}
+}
+
+func TestScanInlineBackticks(t *testing.T) {
+	s := ScannerPool.Get().(*Scanner)
+	defer s.Release()
+
+	// A one-line value ends at the last backtick of its line.
+	res, err := s.Scan([]byte("[a]\nCks: `a` and `b`\nJSON: `{\"k\": \"`v`\"}`\n[\\a]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []string{"a` and `b", "{\"k\": \"`v`\"}"}
+	for i, tt := range tests {
+		ent := res[0].Entries[i]
+		if val := string(res[0].RawData[ent.ValStart:ent.ValEnd]); val != tt {
+			t.Errorf("[%d]: expected %q, got %q", i, tt, val)
+		}
+	}
+}
+
+func TestDetach(t *testing.T) {
+	s := ScannerPool.Get().(*Scanner)
+	defer s.Release()
+
+	res, err := s.Scan([]byte("[a]\nID: 1\n[\\a]\n[b]\nID: 22\n[\\b]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res = Detach(res)
+
+	if _, err := s.Scan([]byte("[c]\nProject: Gurlf\nUser: dev\n[\\c]\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []string{"1", "22"}
+	for i, tt := range tests {
+		ent := res[i].Entries[0]
+		if val := string(res[i].RawData[ent.ValStart:ent.ValEnd]); val != tt {
+			t.Errorf("[%d]: expected %q, got %q", i, tt, val)
+		}
+	}
+}
+
 func BenchmarkScan(b *testing.B) {
 	cfgData := []byte(`
 		[config]
@@ -71,7 +333,7 @@ func TestEmit(t *testing.T) {
 	s.enBuf = s.enBuf[:0]
 	s.dtBuf = s.dtBuf[:0]
 
-	s.emit(cfgData)
+	s.emit(&Data{RawData: cfgData})
 
 	if len(s.enBuf) != len(tests) {
 		t.Errorf("expected %d entries, got %d", len(tests), len(s.enBuf))
@@ -99,7 +361,7 @@ func BenchmarkEmit(b *testing.B) {
 	s.dtBuf = s.dtBuf[:0]
 	for b.Loop() {
 		s.enBuf = s.enBuf[:0]
-		s.emit(cfgData)
+		s.emit(&Data{RawData: cfgData})
 	}
 }
 
@@ -195,6 +457,27 @@ func TestFindEnd(t *testing.T) {
 	}
 }
 
+func TestFindEndSkipsBlocks(t *testing.T) {
+	tests := []struct {
+		input  string
+		expIdx int
+	}{
+		{"Body: `\n[\\cfg]\n`\n[\\cfg]", 17},
+		{"Body: ``\n`\n[\\cfg]\n``\n[\\cfg]", 21},
+		{"Body: `[\\cfg]`\n[\\cfg]", 15},
+	}
+
+	for i, tt := range tests {
+		actIdx, _, err := findEnd([]byte("cfg"), []byte(tt.input))
+		if err != nil {
+			t.Fatalf("[%d]: unexpected error: %v", i, err)
+		}
+		if actIdx != tt.expIdx {
+			t.Errorf("[%d]: expected idx %d, got %d", i, tt.expIdx, actIdx)
+		}
+	}
+}
+
 func BenchmarkFindEnd(b *testing.B) {
 	name := []byte("third$ config")
 	input := []byte("bols [\\third$ config]")
@@ -215,6 +498,10 @@ func TestFindKeyValue(t *testing.T) {
 		{"Body: `115 road\n`", "Body", "115 road\n"},
 		{"Cks: `Maref`", "Cks", "Maref"},
 		{"Body:`\nsomething:\n`else` \n`\n", "Body", "\nsomething:\n`else` \n"},
+		{"Cks: `a` and `b`\nBody: `\nx\n`\n", "Cks", "a` and `b"},
+		{"Body: ``\nline\n`\n[\\cfg]\n``\n", "Body", "line\n`\n[\\cfg]"},
+		{"Body: ```\n``\n\n```", "Body", "``\n"},
+		{"Body: ``\n\n``\n", "Body", ""},
 	}
 
 	for i, tt := range tests {